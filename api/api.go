@@ -5,7 +5,9 @@ import (
 
 	v1 "github.com/mirasildev/note_project/api/v1"
 	"github.com/mirasildev/note_project/config"
+	"github.com/mirasildev/note_project/pkg/utils"
 	"github.com/mirasildev/note_project/storage"
+	"github.com/mirasildev/note_project/storage/repo"
 
 	swaggerFiles "github.com/swaggo/files"     // swagger embed files
 	ginSwagger "github.com/swaggo/gin-swagger" // gin-swagger middleware
@@ -38,26 +40,100 @@ func New(opt *RouterOptions) *gin.Engine {
 	})
 
 	router.Static("/media", "./media")
+	router.GET("/.well-known/jwks.json", handlerV1.GetJWKS)
 
 	apiV1 := router.Group("/v1")
 
-	apiV1.POST("/users", handlerV1.AuthMiddleware, handlerV1.CreateUser)
+	adminOnly := handlerV1.RoleMiddleware(repo.UserRoleAdmin)
+
+	apiV1.POST("/users", handlerV1.AuthMiddleware, adminOnly, handlerV1.CreateUser)
 	apiV1.GET("/users/:id", handlerV1.AuthMiddleware, handlerV1.GetUser)
-	apiV1.GET("/users", handlerV1.GetAllUsers)
-	apiV1.PUT("/users/:id", handlerV1.UpdateUser)
-	apiV1.DELETE("/users/:id", handlerV1.DeleteUser)
+	apiV1.GET("/users", handlerV1.AuthMiddleware, adminOnly, handlerV1.GetAllUsers)
+	apiV1.GET("/audit-logs", handlerV1.AuthMiddleware, adminOnly, handlerV1.GetAuditLogs)
+	apiV1.PUT("/users/:id", handlerV1.AuthMiddleware, handlerV1.UpdateUser)
+	apiV1.DELETE("/users/:id", handlerV1.AuthMiddleware, adminOnly, handlerV1.DeleteUser)
+
+	notesRead := handlerV1.ScopedAuthMiddleware(utils.ScopeNotesRead)
+	notesWrite := handlerV1.ScopedAuthMiddleware(utils.ScopeNotesWrite)
+
+	apiV1.POST("/notes", notesWrite, handlerV1.CreateNote)
+	apiV1.GET("/notes/:id", notesRead, handlerV1.GetNote)
+	apiV1.GET("/notes", notesRead, handlerV1.GetAllNotes)
+	apiV1.PUT("/notes/:id", notesWrite, handlerV1.UpdateNote)
+	apiV1.DELETE("/notes/:id", notesWrite, handlerV1.DeleteNote)
+	apiV1.GET("/notes/trash", notesRead, handlerV1.GetTrash)
+	apiV1.POST("/notes/:id/restore", notesWrite, handlerV1.RestoreNote)
+	apiV1.DELETE("/notes/trash/:id", notesWrite, handlerV1.DeleteNotePermanently)
+	apiV1.POST("/notes/:id/move", notesWrite, handlerV1.MoveNote)
+	apiV1.GET("/notes/:id/revisions", notesRead, handlerV1.GetNoteRevisions)
+	apiV1.GET("/notes/:id/revisions/diff", notesRead, handlerV1.GetNoteRevisionDiff)
+	apiV1.GET("/notes/:id/revisions/:number", notesRead, handlerV1.GetNoteRevision)
+	apiV1.POST("/notes/:id/revisions/:number/restore", notesWrite, handlerV1.RestoreNoteRevision)
 
-	apiV1.POST("/notes", handlerV1.AuthMiddleware, handlerV1.CreateNote)
-	apiV1.GET("/notes/:id", handlerV1.GetNote)
-	apiV1.GET("/notes", handlerV1.GetAllNotes)
-	apiV1.PUT("/notes/:id", handlerV1.UpdateNote)
-	apiV1.DELETE("/notes/:id", handlerV1.DeleteNote)
+	apiV1.POST("/notebooks", notesWrite, handlerV1.CreateNotebook)
+	apiV1.GET("/notebooks/tree", notesRead, handlerV1.GetNotebookTree)
+	apiV1.GET("/notebooks/:id", notesRead, handlerV1.GetNotebook)
+	apiV1.PUT("/notebooks/:id", notesWrite, handlerV1.RenameNotebook)
+	apiV1.POST("/notebooks/:id/move", notesWrite, handlerV1.MoveNotebook)
+	apiV1.DELETE("/notebooks/:id", notesWrite, handlerV1.DeleteNotebook)
+
+	apiV1.GET("/tags", notesRead, handlerV1.GetTags)
+	apiV1.PUT("/tags/:id", notesWrite, handlerV1.RenameTag)
+	apiV1.POST("/tags/:id/merge", notesWrite, handlerV1.MergeTag)
+	apiV1.DELETE("/tags/:id", notesWrite, handlerV1.DeleteTag)
 
 	apiV1.POST("/auth/register", handlerV1.Register)
 	apiV1.POST("/auth/login", handlerV1.Login)
 	apiV1.POST("/auth/verify", handlerV1.Verify)
+	apiV1.POST("/auth/resend-code", handlerV1.ResendCode)
+	apiV1.POST("/auth/refresh", handlerV1.Refresh)
+	apiV1.POST("/auth/logout", handlerV1.AuthMiddleware, handlerV1.Logout)
+	apiV1.POST("/auth/logout-all", handlerV1.AuthMiddleware, handlerV1.LogoutAll)
+	apiV1.GET("/auth/oidc/:provider/login", handlerV1.OidcLogin)
+	apiV1.GET("/auth/oidc/:provider/callback", handlerV1.OidcCallback)
+	apiV1.POST("/auth/magic-link", handlerV1.SendMagicLink)
+	apiV1.GET("/auth/magic-link/callback", handlerV1.MagicLinkCallback)
+	apiV1.POST("/auth/passkey/login/begin", handlerV1.BeginPasskeyLogin)
+	apiV1.POST("/auth/passkey/login/finish", handlerV1.FinishPasskeyLogin)
+
+	apiV1.POST("/auth/2fa/enroll", handlerV1.AuthMiddleware, handlerV1.EnrollTwoFactor)
+	apiV1.POST("/auth/2fa/confirm", handlerV1.AuthMiddleware, handlerV1.ConfirmTwoFactor)
+	apiV1.POST("/auth/2fa/disable", handlerV1.AuthMiddleware, handlerV1.DisableTwoFactor)
+	apiV1.POST("/auth/2fa/recovery-codes", handlerV1.AuthMiddleware, handlerV1.RegenerateRecoveryCodes)
+	apiV1.POST("/auth/2fa/verify", handlerV1.VerifyTwoFactor)
+	apiV1.POST("/auth/forgot-password", handlerV1.ForgotPassword)
+	apiV1.POST("/auth/reset-password", handlerV1.ResetPassword)
+
+	apiV1.DELETE("/me", handlerV1.AuthMiddleware, handlerV1.DeleteAccount)
+	apiV1.GET("/auth/account-deletion/cancel", handlerV1.CancelAccountDeletion)
+	apiV1.PUT("/me/password", handlerV1.AuthMiddleware, handlerV1.ChangePassword)
+	apiV1.GET("/me/revision-settings", handlerV1.AuthMiddleware, handlerV1.GetRevisionSettings)
+	apiV1.PUT("/me/revision-settings", handlerV1.AuthMiddleware, handlerV1.UpdateRevisionSettings)
+	apiV1.POST("/me/email", handlerV1.AuthMiddleware, handlerV1.ChangeEmail)
+	apiV1.POST("/me/email/confirm", handlerV1.AuthMiddleware, handlerV1.ConfirmChangeEmail)
+
+	apiV1.POST("/me/tokens", handlerV1.AuthMiddleware, handlerV1.CreatePersonalAccessToken)
+	apiV1.GET("/me/tokens", handlerV1.AuthMiddleware, handlerV1.GetPersonalAccessTokens)
+	apiV1.DELETE("/me/tokens/:id", handlerV1.AuthMiddleware, handlerV1.DeletePersonalAccessToken)
+
+	apiV1.GET("/me/sessions", handlerV1.AuthMiddleware, handlerV1.GetSessions)
+	apiV1.DELETE("/me/sessions/:id", handlerV1.AuthMiddleware, handlerV1.DeleteSession)
+
+	apiV1.POST("/me/passkeys/register/begin", handlerV1.AuthMiddleware, handlerV1.BeginPasskeyRegistration)
+	apiV1.POST("/me/passkeys/register/finish", handlerV1.AuthMiddleware, handlerV1.FinishPasskeyRegistration)
+	apiV1.GET("/me/passkeys", handlerV1.AuthMiddleware, handlerV1.GetPasskeys)
+	apiV1.DELETE("/me/passkeys/:id", handlerV1.AuthMiddleware, handlerV1.DeletePasskey)
+
+	apiV1.POST("/oauth/clients", handlerV1.AuthMiddleware, handlerV1.CreateOAuthClient)
+	apiV1.GET("/oauth/clients", handlerV1.AuthMiddleware, handlerV1.GetOAuthClients)
+	apiV1.DELETE("/oauth/clients/:id", handlerV1.AuthMiddleware, handlerV1.DeleteOAuthClient)
+	apiV1.GET("/oauth/authorize", handlerV1.AuthMiddleware, handlerV1.OAuthAuthorize)
+	apiV1.POST("/oauth/authorize", handlerV1.AuthMiddleware, handlerV1.OAuthConsent)
+	apiV1.POST("/oauth/token", handlerV1.OAuthToken)
+	apiV1.POST("/oauth/introspect", handlerV1.OAuthIntrospect)
+	apiV1.POST("/oauth/revoke", handlerV1.OAuthRevoke)
 
-	apiV1.POST("/file-upload", handlerV1.AuthMiddleware, handlerV1.UploadFile)
+	apiV1.POST("/file-upload", handlerV1.ScopedAuthMiddleware(utils.ScopeFilesWrite), handlerV1.UploadFile)
 
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
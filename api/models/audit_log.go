@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+type AuditLog struct {
+	ID        int64     `json:"id"`
+	UserID    *int64    `json:"user_id"`
+	Email     string    `json:"email"`
+	Event     string    `json:"event"`
+	IPAddress string    `json:"ip_address"`
+	UserAgent string    `json:"user_agent"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type GetAllAuditLogsParams struct {
+	Limit  int32  `json:"limit" binding:"required" default:"10"`
+	Page   int32  `json:"page" binding:"required" default:"1"`
+	UserID *int64 `json:"user_id"`
+	Event  string `json:"event" enums:"register,verify,login_succeeded,login_failed,password_changed,token_revoked"`
+	// From and To limit the time range, in RFC 3339
+	From *time.Time `json:"from"`
+	To   *time.Time `json:"to"`
+}
+
+type GetAllAuditLogsResponse struct {
+	AuditLogs []*AuditLog `json:"audit_logs"`
+	Count     int32       `json:"count"`
+}
@@ -6,7 +6,7 @@ type RegisterRequest struct {
 	FirstName string `json:"first_name" binding:"required,min=2,max=30"`
 	LastName  string `json:"last_name" binding:"required,min=2,max=30"`
 	Email     string `json:"email" binding:"required,email"`
-	Password  string `json:"password" binding:"required,min=6,max=16"`
+	Password  string `json:"password" binding:"required"`
 }
 
 type AuthResponse struct {
@@ -17,13 +17,64 @@ type AuthResponse struct {
 	Username    string    `json:"username"`
 	CreatedAt   time.Time `json:"created_at"`
 	AccessToken string    `json:"access_token"`
+	ExpiresAt   time.Time `json:"expires_at"`
+
+	RefreshToken string `json:"refresh_token"`
+}
+
+type DeleteAccountResponse struct {
+	// DeletionScheduledAt is when the account is deleted for good
+	DeletionScheduledAt time.Time `json:"deletion_scheduled_at"`
 }
 
 type LoginRequest struct {
-	Email string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,min=6,max=16"`
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required"`
 }
 
 type UpdatePassword struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	Password        string `json:"password" binding:"required"`
+}
+
+type ChangeEmailRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ConfirmChangeEmailRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type MagicLinkRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Email    string `json:"email" binding:"required,email"`
+	Code     string `json:"code" binding:"required"`
 	Password string `json:"password" binding:"required"`
-}
\ No newline at end of file
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type ResendCodeRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// VerificationErrorResponse tells the client in which state
+// the pending registration is after a failed verification
+type VerificationErrorResponse struct {
+	Error        string `json:"error"`
+	Status       string `json:"status"`
+	AttemptsLeft int    `json:"attempts_left,omitempty"`
+}
@@ -5,23 +5,45 @@ import "time"
 type Note struct {
 	ID          int64      `json:"id"`
 	UserID      int64      `json:"user_id"`
+	NotebookID  *int64     `json:"notebook_id"`
 	Title       string     `json:"title"`
 	Description *string    `json:"description"`
 	CreatedAt   time.Time  `json:"created_at"`
 	UpdatedAt   *time.Time `json:"updated_at"`
 	DeletedAt   *time.Time `json:"deleted_at"`
+	// Version is also returned as the ETag of the note
+	Version int64    `json:"version"`
+	Tags    []string `json:"tags"`
+	// Highlight is only returned when searching
+	Highlight *NoteHighlight `json:"highlight,omitempty"`
+}
+
+// NoteHighlight holds HTML escaped snippets of a note with the matched words
+// wrapped in <mark> tags
+type NoteHighlight struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
 }
 
 type CreateNoteRequest struct {
-	UserID      int64   `json:"user_id" binding:"required"`
-	Title       string  `json:"title" binding:"required,max=100"`
-	Description *string `json:"description"`
+	Title       string   `json:"title" binding:"required,max=100"`
+	Description *string  `json:"description" binding:"omitempty,max=100000"`
+	Tags        []string `json:"tags" binding:"max=20,dive,max=50"`
+	NotebookID  *int64   `json:"notebook_id"`
 }
 
 type GetAllNotesParams struct {
-	Limit  int32 `json:"limit" binding:"required" default:"10"`
-	Page   int32 `json:"page" binding:"required" default:"1"`
-	UserID int64 `json:"user_id" binding:"required"`
+	Limit int32 `json:"limit" binding:"required" default:"10"`
+	Page  int32 `json:"page" binding:"required" default:"1"`
+	// Search supports "quoted phrases", or and -exclusions
+	Search string `json:"search"`
+	// Tags is a comma separated list of tags
+	Tags string `json:"tags"`
+	// TagsMatch tells whether the notes should have any or all of the tags
+	TagsMatch  string `json:"tags_match" enums:"any,all" default:"any"`
+	NotebookID int64  `json:"notebook_id"`
+	// Recursive includes the notes of the notebooks inside of the notebook
+	Recursive bool `json:"recursive"`
 }
 
 type GetAllNotesResponse struct {
@@ -30,7 +52,8 @@ type GetAllNotesResponse struct {
 }
 
 type UpdateNoteRequest struct {
-	UserID      int64      `json:"user_id"`
-	Title       string     `json:"title"`
-	Description *string    `json:"description"`
+	Title       string  `json:"title" binding:"required,max=100"`
+	Description *string `json:"description" binding:"omitempty,max=100000"`
+	// Tags replace the tags of the note. The tags are kept when it is omitted
+	Tags *[]string `json:"tags" binding:"omitempty,max=20,dive,max=50"`
 }
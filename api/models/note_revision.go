@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+type NoteRevision struct {
+	Number      int    `json:"number"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	// AuthorID is null when the author was deleted
+	AuthorID  *int64    `json:"author_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type GetAllNoteRevisionsResponse struct {
+	Revisions []*NoteRevision `json:"revisions"`
+}
+
+type DiffLine struct {
+	Op   string `json:"op" enums:"equal,insert,delete"`
+	Text string `json:"text"`
+}
+
+type NoteRevisionDiffResponse struct {
+	From        int         `json:"from"`
+	To          int         `json:"to"`
+	Title       []*DiffLine `json:"title"`
+	Description []*DiffLine `json:"description"`
+}
+
+type RevisionSettings struct {
+	// Limit is how many revisions are kept per note
+	Limit int `json:"limit"`
+}
+
+type UpdateRevisionSettingsRequest struct {
+	// Limit is null to go back to the default of the service
+	Limit *int `json:"limit" binding:"omitempty,min=1,max=1000"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+type Notebook struct {
+	ID int64 `json:"id"`
+	// ParentID is null for the notebooks at the top level
+	ParentID  *int64     `json:"parent_id"`
+	Name      string     `json:"name"`
+	NoteCount int64      `json:"note_count"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at"`
+}
+
+type NotebookTree struct {
+	Notebook
+	Children []*NotebookTree `json:"children"`
+}
+
+type GetNotebookTreeResponse struct {
+	Notebooks []*NotebookTree `json:"notebooks"`
+}
+
+type CreateNotebookRequest struct {
+	Name     string `json:"name" binding:"required,max=100"`
+	ParentID *int64 `json:"parent_id"`
+}
+
+type RenameNotebookRequest struct {
+	Name string `json:"name" binding:"required,max=100"`
+}
+
+type MoveNotebookRequest struct {
+	// ParentID is null to move the notebook to the top level
+	ParentID *int64 `json:"parent_id"`
+}
+
+type MoveNoteRequest struct {
+	// NotebookID is null to take the note out of its notebook
+	NotebookID *int64 `json:"notebook_id"`
+}
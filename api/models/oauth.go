@@ -0,0 +1,84 @@
+package models
+
+import "time"
+
+type OAuthClient struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	Scopes       []string  `json:"scopes"`
+	Confidential bool      `json:"confidential"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type CreateOAuthClientRequest struct {
+	Name         string   `json:"name" binding:"required,max=100"`
+	RedirectURIs []string `json:"redirect_uris" binding:"required,min=1,dive,url"`
+	Scopes       []string `json:"scopes" binding:"required,min=1,dive,oneof=notes:read notes:write files:write"`
+	// Confidential clients get a secret. Browser extensions and
+	// desktop apps can't keep one and should use PKCE alone.
+	Confidential bool `json:"confidential"`
+}
+
+type CreateOAuthClientResponse struct {
+	OAuthClient
+	// ClientSecret is shown only once, it can't be retrieved later
+	ClientSecret string `json:"client_secret,omitempty"`
+}
+
+type GetAllOAuthClientsResponse struct {
+	Clients []*OAuthClient `json:"clients"`
+}
+
+type OAuthAuthorizeRequest struct {
+	ResponseType        string `json:"response_type" form:"response_type" binding:"required,eq=code"`
+	ClientID            string `json:"client_id" form:"client_id" binding:"required"`
+	RedirectURI         string `json:"redirect_uri" form:"redirect_uri" binding:"required"`
+	Scope               string `json:"scope" form:"scope" binding:"required"`
+	State               string `json:"state" form:"state"`
+	CodeChallenge       string `json:"code_challenge" form:"code_challenge" binding:"required"`
+	CodeChallengeMethod string `json:"code_challenge_method" form:"code_challenge_method" binding:"required,eq=S256"`
+}
+
+type OAuthConsentRequest struct {
+	OAuthAuthorizeRequest
+	// Approve is false when the user denies the access
+	Approve bool `json:"approve"`
+}
+
+// OAuthConsentResponse describes what the client asks for,
+// so the consent screen can show it to the user
+type OAuthConsentResponse struct {
+	ClientID    string   `json:"client_id"`
+	ClientName  string   `json:"client_name"`
+	Scopes      []string `json:"scopes"`
+	RedirectURI string   `json:"redirect_uri"`
+	State       string   `json:"state"`
+}
+
+type OAuthRedirectResponse struct {
+	RedirectTo string `json:"redirect_to"`
+}
+
+type OAuthTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+	Scope        string `json:"scope"`
+}
+
+type OAuthErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+type OAuthIntrospectionResponse struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Sub       string `json:"sub,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	Exp       int64  `json:"exp,omitempty"`
+	Iat       int64  `json:"iat,omitempty"`
+}
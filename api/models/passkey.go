@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"github.com/mirasildev/note_project/pkg/webauthn"
+)
+
+type Passkey struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type GetAllPasskeysResponse struct {
+	Passkeys []*Passkey `json:"passkeys"`
+}
+
+type FinishPasskeyRegistrationRequest struct {
+	Name       string                       `json:"name" binding:"required,max=100"`
+	Credential webauthn.AttestationResponse `json:"credential" binding:"required"`
+}
+
+type BeginPasskeyLoginRequest struct {
+	// Email is optional, without it any passkey of the site may be used
+	Email string `json:"email" binding:"omitempty,email"`
+}
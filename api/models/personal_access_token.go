@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+type PersonalAccessToken struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type CreatePersonalAccessTokenRequest struct {
+	Name      string     `json:"name" binding:"required,max=100"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1,dive,oneof=notes:read notes:write files:write"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type CreatePersonalAccessTokenResponse struct {
+	PersonalAccessToken
+	// Token is shown only once, it can't be retrieved later
+	Token string `json:"token"`
+}
+
+type GetAllPersonalAccessTokensResponse struct {
+	Tokens []*PersonalAccessToken `json:"tokens"`
+}
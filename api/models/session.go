@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+type Session struct {
+	ID string `json:"id"`
+	// ClientID is set for sessions of third-party apps authorized over OAuth
+	ClientID   *string   `json:"client_id"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	// Current is true for the session of the token that made the request
+	Current bool `json:"current"`
+}
+
+type GetAllSessionsResponse struct {
+	Sessions []*Session `json:"sessions"`
+}
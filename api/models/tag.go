@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+type Tag struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	NoteCount int64     `json:"note_count"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type GetAllTagsResponse struct {
+	Tags []*Tag `json:"tags"`
+}
+
+type RenameTagRequest struct {
+	Name string `json:"name" binding:"required,max=50"`
+}
+
+type MergeTagRequest struct {
+	// TargetID is the tag which the notes are moved to
+	TargetID int64 `json:"target_id" binding:"required"`
+}
@@ -9,6 +9,7 @@ type User struct {
 	PhoneNumber *string   `json:"phone_number"`
 	Email       string    `json:"email"`
 	ImageURL    *string   `json:"image_url"`
+	Role        string    `json:"role"`
 	CreatedAt   time.Time `json:"created_at"`
 }
 
@@ -17,7 +18,9 @@ type CreateUserRequest struct {
 	LastName    string  `json:"last_name" binding:"required,min=2,max=30"`
 	PhoneNumber *string `json:"phone_number"` // *
 	Email       string  `json:"email" binding:"required,email"`
+	Password    string  `json:"password" binding:"required"`
 	ImageURL    *string `json:"image_url"` // *
+	Role        string  `json:"role" binding:"omitempty,oneof=user admin"`
 }
 
 type GetAllUsersResponse struct {
@@ -35,7 +38,6 @@ type UpdateUserRequest struct {
 	FirstName   string    `json:"first_name" binding:"required,min=2,max=30"`
 	LastName    string    `json:"last_name" binding:"required,min=2,max=30"`
 	PhoneNumber *string   `json:"phone_number"` // *
-	Email       string    `json:"email" binding:"required,email"`
 	ImageURL    *string   `json:"image_url"` // *
 	// UpdatedAt   time.Time `json:"updated_at"`
 }
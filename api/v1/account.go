@@ -0,0 +1,310 @@
+package v1
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mirasildev/note_project/api/models"
+	emailPkg "github.com/mirasildev/note_project/pkg/email"
+	"github.com/mirasildev/note_project/pkg/utils"
+	"github.com/mirasildev/note_project/storage"
+	"github.com/mirasildev/note_project/storage/repo"
+)
+
+const (
+	ChangeEmailKey  = "change_email_"
+	ChangeEmailCode = "change_email_code_"
+)
+
+const (
+	changeEmailAction    = "change_email"
+	changePasswordAction = "change_password"
+)
+
+var (
+	ErrWrongPassword = errors.New("current password is incorrect")
+	ErrSameEmail     = errors.New("new email is the same as the current one")
+)
+
+// @Security ApiKeyAuth
+// @Router /me/password [put]
+// @Summary Change password
+// @Description Change the password of the authenticated user. Every other session is signed out and the personal access tokens are deleted
+// @Tags account
+// @Accept json
+// @Produce json
+// @Param data body models.UpdatePassword true "Data"
+// @Success 200 {object} models.AuthResponse
+// @Failure 429 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) ChangePassword(c *gin.Context) {
+	var req models.UpdatePassword
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if !h.validatePassword(c, req.Password) {
+		return
+	}
+
+	payload, err := h.GetAuthPayload(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if !h.checkAttempts(c, changePasswordAction, payload.Email) {
+		return
+	}
+
+	user, err := h.storage.User().Get(payload.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	_, err = utils.CheckPassword(h.cfg, req.CurrentPassword, user.Password)
+	if err != nil {
+		err = h.registerFailedAttempt(c, changePasswordAction, payload.Email)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		c.JSON(http.StatusForbidden, errorResponse(ErrWrongPassword))
+		return
+	}
+
+	err = h.resetAttempts(changePasswordAction, payload.Email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	hashedPassword, err := utils.HashPassword(h.cfg, req.Password)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = h.storage.User().UpdatePassword(user.ID, hashedPassword)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// a token created by whoever knew the old password would outlive the change
+	err = h.storage.PersonalAccessToken().DeleteAll(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	h.audit(c, repo.AuditEventPasswordChanged, user.ID, user.Email)
+
+	resp, err := h.renewSessions(c, user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// @Security ApiKeyAuth
+// @Router /me/email [post]
+// @Summary Change email
+// @Description Send a confirmation code to the new email. The email is changed by /me/email/confirm
+// @Tags account
+// @Accept json
+// @Produce json
+// @Param data body models.ChangeEmailRequest true "Data"
+// @Success 200 {object} models.ResponseOK
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) ChangeEmail(c *gin.Context) {
+	var req models.ChangeEmailRequest
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := h.GetAuthPayload(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if req.Email == payload.Email {
+		c.JSON(http.StatusBadRequest, errorResponse(ErrSameEmail))
+		return
+	}
+
+	_, err = h.storage.User().GetByEmail(req.Email)
+	if !errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusBadRequest, errorResponse(ErrEmailExists))
+		return
+	}
+
+	userID := strconv.FormatInt(payload.UserID, 10)
+
+	err = h.inMemory.Set(ChangeEmailKey+userID, req.Email, verificationCodeTTL)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	go func() {
+		err := h.sendVerificationCode(ChangeEmailCode+userID+"_", req.Email, emailPkg.VerificationEmail)
+		if err != nil {
+			fmt.Printf("Failed to send change email code: %v", err)
+		}
+	}()
+
+	c.JSON(http.StatusOK, models.ResponseOK{
+		Message: "Verification code has been sent to the new email!",
+	})
+}
+
+// @Security ApiKeyAuth
+// @Router /me/email/confirm [post]
+// @Summary Confirm email change
+// @Description Switch to the new email using the code sent to it. Every other session is signed out
+// @Tags account
+// @Accept json
+// @Produce json
+// @Param data body models.ConfirmChangeEmailRequest true "Data"
+// @Success 200 {object} models.AuthResponse
+// @Failure 429 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) ConfirmChangeEmail(c *gin.Context) {
+	var req models.ConfirmChangeEmailRequest
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := h.GetAuthPayload(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if !h.checkAttempts(c, changeEmailAction, payload.Email) {
+		return
+	}
+
+	userID := strconv.FormatInt(payload.UserID, 10)
+
+	newEmail, err := h.inMemory.Get(ChangeEmailKey + userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrKeyNotFound) {
+			c.JSON(http.StatusForbidden, errorResponse(ErrCodeExpired))
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	code, err := h.inMemory.Get(ChangeEmailCode + userID + "_" + newEmail)
+	if err != nil {
+		if errors.Is(err, storage.ErrKeyNotFound) {
+			c.JSON(http.StatusForbidden, errorResponse(ErrCodeExpired))
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if req.Code != code {
+		err = h.registerFailedAttempt(c, changeEmailAction, payload.Email)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		c.JSON(http.StatusForbidden, errorResponse(ErrIncorrectCode))
+		return
+	}
+
+	// The email might have been registered since the code was sent
+	_, err = h.storage.User().GetByEmail(newEmail)
+	if !errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusBadRequest, errorResponse(ErrEmailExists))
+		return
+	}
+
+	err = h.storage.User().UpdateEmail(payload.UserID, newEmail)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	for _, key := range []string{ChangeEmailKey + userID, ChangeEmailCode + userID + "_" + newEmail} {
+		err = h.inMemory.Delete(key)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+	}
+
+	err = h.resetAttempts(changeEmailAction, payload.Email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp, err := h.renewSessions(c, payload.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// validatePassword responds with 400 if the password breaks the password policy.
+// It reports whether the request may go on.
+func (h *handlerV1) validatePassword(c *gin.Context, password string) bool {
+	err := utils.ValidatePassword(h.cfg, password)
+	if err != nil {
+		if errors.Is(err, utils.ErrWeakPassword) {
+			c.JSON(http.StatusBadRequest, errorResponse(err))
+			return false
+		}
+
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return false
+	}
+
+	return true
+}
+
+// renewSessions revokes every token of the user and returns
+// new ones for the client which made the change
+func (h *handlerV1) renewSessions(c *gin.Context, userID int64) (*models.AuthResponse, error) {
+	err := h.revokeUserTokens(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := h.storage.User().Get(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return h.newAuthResponse(c, user)
+}
@@ -0,0 +1,226 @@
+package v1
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mirasildev/note_project/api/models"
+	emailPkg "github.com/mirasildev/note_project/pkg/email"
+	"github.com/mirasildev/note_project/pkg/utils"
+	"github.com/mirasildev/note_project/storage/repo"
+)
+
+const cancelAccountDeletionAction = "cancel_account_deletion"
+
+var (
+	ErrAccountDeletionScheduled = errors.New("account is scheduled for deletion, use the link from the email to cancel it")
+	ErrInvalidCancelLink        = errors.New("cancellation link is invalid or the account has already been deleted")
+)
+
+// @Security ApiKeyAuth
+// @Router /me [delete]
+// @Summary Delete account
+// @Description Schedule the deletion of the account after a grace period. Every session is signed out
+// @Description and signing in is blocked until the deletion is cancelled from the link sent by email
+// @Tags account
+// @Accept json
+// @Produce json
+// @Success 200 {object} models.DeleteAccountResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) DeleteAccount(c *gin.Context) {
+	payload, err := h.GetAuthPayload(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	user, err := h.storage.User().Get(payload.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if user.DeletionScheduledAt != nil {
+		c.JSON(http.StatusOK, models.DeleteAccountResponse{
+			DeletionScheduledAt: *user.DeletionScheduledAt,
+		})
+		return
+	}
+
+	deletionAt := time.Now().Add(h.cfg.AccountDeletionGracePeriod)
+
+	err = h.storage.User().ScheduleDeletion(user.ID, deletionAt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = h.revokeUserTokens(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	go func() {
+		err := h.sendAccountDeletionEmail(user, deletionAt)
+		if err != nil {
+			fmt.Printf("Failed to send account deletion email: %v", err)
+		}
+	}()
+
+	c.JSON(http.StatusOK, models.DeleteAccountResponse{
+		DeletionScheduledAt: deletionAt,
+	})
+}
+
+func (h *handlerV1) sendAccountDeletionEmail(user *repo.User, deletionAt time.Time) error {
+	link, err := url.Parse(h.cfg.AccountDeletionCancelURL)
+	if err != nil {
+		return err
+	}
+
+	// expires is the time the deletion is scheduled at, the link
+	// only cancels that deletion and not one scheduled later again
+	userID := strconv.FormatInt(user.ID, 10)
+	expires := strconv.FormatInt(deletionAt.Unix(), 10)
+	link.RawQuery = url.Values{
+		"user_id":   {userID},
+		"expires":   {expires},
+		"signature": {utils.Sign(h.cfg.AuthSecretKey, cancelAccountDeletionAction, userID, expires)},
+	}.Encode()
+
+	return emailPkg.SendEmail(h.cfg, &emailPkg.SendEmailRequest{
+		To:      []string{user.Email},
+		Subject: "Your account is scheduled for deletion",
+		Body: map[string]string{
+			"link": link.String(),
+			"date": deletionAt.Format(time.RFC1123),
+		},
+		Type: emailPkg.AccountDeletionEmail,
+	})
+}
+
+// @Router /auth/account-deletion/cancel [get]
+// @Summary Cancel account deletion
+// @Description Keep the account using the link from the account deletion email
+// @Tags account
+// @Produce json
+// @Param user_id query string true "User ID"
+// @Param expires query string true "Expires"
+// @Param signature query string true "Signature"
+// @Success 200 {object} models.ResponseOK
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) CancelAccountDeletion(c *gin.Context) {
+	userID := c.Query("user_id")
+	expires := c.Query("expires")
+
+	if !utils.VerifySignature(h.cfg.AuthSecretKey, c.Query("signature"), cancelAccountDeletionAction, userID, expires) {
+		c.JSON(http.StatusBadRequest, errorResponse(ErrInvalidCancelLink))
+		return
+	}
+
+	id, err := strconv.ParseInt(userID, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(ErrInvalidCancelLink))
+		return
+	}
+
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > expiresAt {
+		c.JSON(http.StatusBadRequest, errorResponse(ErrInvalidCancelLink))
+		return
+	}
+
+	err = h.storage.User().CancelDeletion(id, time.Unix(expiresAt, 0))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusBadRequest, errorResponse(ErrInvalidCancelLink))
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.ResponseOK{
+		Message: "Account deletion has been cancelled, you can sign in again!",
+	})
+}
+
+// RunAccountPurge purges the accounts past their grace period
+// every AccountPurgeInterval until ctx is done
+func (h *handlerV1) RunAccountPurge(ctx context.Context) {
+	runPeriodically(ctx, h.cfg.AccountPurgeInterval, h.purgeDeletedAccounts)
+}
+
+func (h *handlerV1) purgeDeletedAccounts() {
+	ids, err := h.storage.User().GetScheduledForDeletion(time.Now())
+	if err != nil {
+		log.Printf("failed to get accounts scheduled for deletion: %v", err)
+		return
+	}
+
+	for _, id := range ids {
+		err = h.purgeUser(id)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("failed to purge user %d: %v", id, err)
+		}
+	}
+}
+
+// purgeUser deletes the user with all of the data,
+// then removes the uploads and the cache keys left behind
+func (h *handlerV1) purgeUser(userID int64) error {
+	purged, err := h.storage.User().Purge(userID)
+	if err != nil {
+		return err
+	}
+
+	// the user is gone from the database, so a file left behind now is never
+	// retried. Every file is tried and the failures are only logged.
+	for _, path := range purged.FilePaths {
+		err = os.Remove(filepath.Join(".", path))
+		if err != nil && !os.IsNotExist(err) {
+			log.Printf("failed to remove file %s of user %d: %v", path, purged.ID, err)
+		}
+	}
+
+	id := strconv.FormatInt(purged.ID, 10)
+	keys := []string{
+		UserTokensRevokedKey + id,
+		ChangeEmailKey + id,
+		PasskeyRegistrationKey + id,
+		ForgotPasswordKey + purged.Email,
+		MagicLinkCooldownKey + purged.Email,
+	}
+	for _, sessionID := range purged.SessionIDs {
+		keys = append(keys, RefreshTokenFamilyKey+sessionID, SessionSeenKey+sessionID)
+	}
+	for _, action := range []string{loginAction, verifyAction, twoFactorAction, changeEmailAction, changePasswordAction} {
+		keys = append(keys,
+			attemptsKey(FailedAttemptsKey, action, "email", purged.Email),
+			attemptsKey(AttemptsLockKey, action, "email", purged.Email),
+		)
+	}
+
+	for _, key := range keys {
+		err = h.inMemory.Delete(key)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
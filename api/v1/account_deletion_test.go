@@ -0,0 +1,85 @@
+package v1
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mirasildev/note_project/storage/repo"
+)
+
+// purgedUsers purges every user it is asked for
+type purgedUsers struct {
+	fakeUsers
+	purged *repo.PurgedUser
+}
+
+func (u *purgedUsers) Purge(userID int64) (*repo.PurgedUser, error) {
+	return u.purged, nil
+}
+
+// inTempDir runs the test in an empty directory, like the one the server serves ./media from
+func inTempDir(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+
+	require.NoError(t, os.Chdir(t.TempDir()))
+	t.Cleanup(func() {
+		require.NoError(t, os.Chdir(wd))
+	})
+
+	require.NoError(t, os.MkdirAll("media", 0o755))
+}
+
+func TestPurgeUserRemovesEveryFile(t *testing.T) {
+	inTempDir(t)
+
+	// a non-empty directory can't be removed, the files after it still have to be
+	require.NoError(t, os.MkdirAll(filepath.Join("media", "stuck", "inside"), 0o755))
+	for _, path := range []string{"media/first.png", "media/last.png"} {
+		require.NoError(t, os.WriteFile(path, []byte("image"), 0o644))
+	}
+
+	h := newTestHandler(&fakeStorage{
+		users: &purgedUsers{
+			purged: &repo.PurgedUser{
+				ID:        1,
+				Email:     testEmail,
+				FilePaths: []string{"media/first.png", "media/stuck", "media/missing.png", "media/last.png"},
+			},
+		},
+	})
+
+	require.NoError(t, h.purgeUser(1))
+
+	for _, path := range []string{"media/first.png", "media/last.png"} {
+		_, err := os.Stat(path)
+		require.True(t, os.IsNotExist(err), path)
+	}
+}
+
+func TestPurgeUserUnlocksEmail(t *testing.T) {
+	h := newTestHandler(&fakeStorage{
+		users: &purgedUsers{
+			purged: &repo.PurgedUser{ID: 1, Email: testEmail},
+		},
+	})
+
+	actions := []string{loginAction, verifyAction, twoFactorAction, changeEmailAction, changePasswordAction}
+	for _, action := range actions {
+		for i := 0; i < lockAfterAttempts; i++ {
+			c, _ := newAttemptContext("10.0.0.1")
+			require.NoError(t, h.registerFailedAttempt(c, action, testEmail))
+		}
+	}
+
+	require.NoError(t, h.purgeUser(1))
+
+	// whoever registers the email again starts without failures
+	for _, action := range actions {
+		c, _ := newAttemptContext("10.0.0.2")
+		require.True(t, h.checkAttempts(c, action, testEmail), action)
+	}
+}
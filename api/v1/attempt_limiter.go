@@ -0,0 +1,144 @@
+package v1
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	emailPkg "github.com/mirasildev/note_project/pkg/email"
+)
+
+const (
+	FailedAttemptsKey = "failed_attempts_"
+	AttemptsLockKey   = "attempts_lock_"
+)
+
+// Actions which are protected against brute-force
+const (
+	loginAction     = "login"
+	verifyAction    = "verify"
+	twoFactorAction = "two_factor"
+)
+
+const (
+	// failedAttemptsWindow is how long failed attempts are remembered
+	failedAttemptsWindow = time.Hour
+	// delayAfterAttempts is the number of failures per email after which
+	// every next attempt has to wait twice as long as the previous one
+	delayAfterAttempts = 3
+	maxAttemptsDelay   = time.Minute
+	// lockAfterAttempts is the number of failures per email which locks the account
+	lockAfterAttempts = 10
+	// lockIPAfterAttempts is the number of failures from one IP, for any email, which locks the IP
+	lockIPAfterAttempts = 50
+	lockDuration        = 15 * time.Minute
+)
+
+var ErrTooManyAttempts = errors.New("too many failed attempts, try again later")
+
+// checkAttempts aborts the request with 429 and a Retry-After header if the email
+// or the client IP is locked for the action. It reports whether the request may go on.
+func (h *handlerV1) checkAttempts(c *gin.Context, action, email string) bool {
+	retryAfter := time.Duration(0)
+
+	for _, key := range []string{
+		attemptsKey(AttemptsLockKey, action, "email", email),
+		attemptsKey(AttemptsLockKey, action, "ip", c.ClientIP()),
+	} {
+		ttl, err := h.inMemory.TTL(key)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, errorResponse(err))
+			return false
+		}
+
+		if ttl > retryAfter {
+			retryAfter = ttl
+		}
+	}
+
+	if retryAfter > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, errorResponse(ErrTooManyAttempts))
+		return false
+	}
+
+	return true
+}
+
+// registerFailedAttempt counts a failure for the email and the client IP
+// and delays or locks further attempts once the limits are reached
+func (h *handlerV1) registerFailedAttempt(c *gin.Context, action, email string) error {
+	emailFailures, err := h.inMemory.Incr(attemptsKey(FailedAttemptsKey, action, "email", email), failedAttemptsWindow)
+	if err != nil {
+		return err
+	}
+
+	ipFailures, err := h.inMemory.Incr(attemptsKey(FailedAttemptsKey, action, "ip", c.ClientIP()), failedAttemptsWindow)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case emailFailures >= lockAfterAttempts:
+		err = h.inMemory.Set(attemptsKey(AttemptsLockKey, action, "email", email), "1", lockDuration)
+		if err != nil {
+			return err
+		}
+
+		if emailFailures == lockAfterAttempts {
+			go h.sendAccountLockedEmail(email)
+		}
+	case emailFailures >= delayAfterAttempts:
+		delay := time.Second << (emailFailures - delayAfterAttempts)
+		if delay > maxAttemptsDelay {
+			delay = maxAttemptsDelay
+		}
+
+		err = h.inMemory.Set(attemptsKey(AttemptsLockKey, action, "email", email), "1", delay)
+		if err != nil {
+			return err
+		}
+	}
+
+	if ipFailures >= lockIPAfterAttempts {
+		err = h.inMemory.Set(attemptsKey(AttemptsLockKey, action, "ip", c.ClientIP()), "1", lockDuration)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// resetAttempts forgets the failures of the email after a successful attempt
+func (h *handlerV1) resetAttempts(action, email string) error {
+	return h.inMemory.Delete(attemptsKey(FailedAttemptsKey, action, "email", email))
+}
+
+func (h *handlerV1) sendAccountLockedEmail(email string) {
+	_, err := h.storage.User().GetByEmail(email)
+	if err != nil {
+		return
+	}
+
+	err = emailPkg.SendEmail(h.cfg, &emailPkg.SendEmailRequest{
+		To:      []string{email},
+		Subject: "Your account has been locked",
+		Body: map[string]string{
+			"duration": lockDuration.String(),
+		},
+		Type: emailPkg.AccountLockedEmail,
+	})
+	if err != nil {
+		fmt.Printf("Failed to send account locked email: %v", err)
+	}
+}
+
+func attemptsKey(prefix, action, kind, value string) string {
+	return prefix + action + "_" + kind + "_" + value
+}
@@ -0,0 +1,130 @@
+package v1
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+const testEmail = "john@example.com"
+
+func newAttemptsHandler() (*handlerV1, *fakeInMemory) {
+	h := newTestHandler(&fakeStorage{users: &fakeUsers{}})
+	return h, h.inMemory.(*fakeInMemory)
+}
+
+func newAttemptContext(ip string) (*gin.Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/auth/login", nil)
+	c.Request.RemoteAddr = ip + ":1234"
+
+	return c, w
+}
+
+// retryAfter returns the Retry-After seconds, or "" if the attempt may go on
+func retryAfter(t *testing.T, h *handlerV1, ip, email string) string {
+	c, w := newAttemptContext(ip)
+
+	if h.checkAttempts(c, loginAction, email) {
+		return ""
+	}
+
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	require.NotEmpty(t, w.Header().Get("Retry-After"))
+
+	return w.Header().Get("Retry-After")
+}
+
+func failAttempt(t *testing.T, h *handlerV1, ip, email string) {
+	c, _ := newAttemptContext(ip)
+	require.NoError(t, h.registerFailedAttempt(c, loginAction, email))
+}
+
+func TestAttemptsProgressiveDelay(t *testing.T) {
+	h, mem := newAttemptsHandler()
+
+	for i := 1; i < delayAfterAttempts; i++ {
+		failAttempt(t, h, "10.0.0.1", testEmail)
+		require.Empty(t, retryAfter(t, h, "10.0.0.1", testEmail))
+	}
+
+	for i, delay := range []int{1, 2, 4, 8, 16, 32, 60} {
+		failAttempt(t, h, "10.0.0.1", testEmail)
+		require.Equal(t, fmt.Sprint(delay), retryAfter(t, h, "10.0.0.1", testEmail), "failure %d", delayAfterAttempts+i)
+
+		mem.advance(time.Duration(delay) * time.Second)
+		require.Empty(t, retryAfter(t, h, "10.0.0.1", testEmail))
+	}
+}
+
+func TestAttemptsLockAccount(t *testing.T) {
+	h, mem := newAttemptsHandler()
+
+	for i := 0; i < lockAfterAttempts; i++ {
+		mem.advance(maxAttemptsDelay)
+		failAttempt(t, h, "10.0.0.1", testEmail)
+	}
+
+	require.Equal(t, "900", retryAfter(t, h, "10.0.0.1", testEmail))
+	// the lock is on the email, another IP doesn't get around it
+	require.Equal(t, "900", retryAfter(t, h, "10.0.0.2", testEmail))
+	require.Empty(t, retryAfter(t, h, "10.0.0.1", "jane@example.com"))
+
+	mem.advance(lockDuration)
+	require.Empty(t, retryAfter(t, h, "10.0.0.1", testEmail))
+}
+
+func TestAttemptsResetOnSuccess(t *testing.T) {
+	h, _ := newAttemptsHandler()
+
+	for i := 1; i < delayAfterAttempts; i++ {
+		failAttempt(t, h, "10.0.0.1", testEmail)
+	}
+
+	require.NoError(t, h.resetAttempts(loginAction, testEmail))
+
+	for i := 1; i < delayAfterAttempts; i++ {
+		failAttempt(t, h, "10.0.0.1", testEmail)
+		require.Empty(t, retryAfter(t, h, "10.0.0.1", testEmail))
+	}
+}
+
+func TestAttemptsLockIP(t *testing.T) {
+	h, _ := newAttemptsHandler()
+
+	for i := 0; i < lockIPAfterAttempts; i++ {
+		failAttempt(t, h, "10.0.0.1", fmt.Sprintf("user%d@example.com", i))
+	}
+
+	require.Equal(t, "900", retryAfter(t, h, "10.0.0.1", "new@example.com"))
+	require.Empty(t, retryAfter(t, h, "10.0.0.2", "new@example.com"))
+}
+
+func TestAttemptsRetryAfterRoundsUp(t *testing.T) {
+	h, mem := newAttemptsHandler()
+
+	for i := 0; i < delayAfterAttempts+1; i++ {
+		failAttempt(t, h, "10.0.0.1", testEmail)
+	}
+	require.Equal(t, "2", retryAfter(t, h, "10.0.0.1", testEmail))
+
+	mem.advance(1500 * time.Millisecond)
+	require.Equal(t, "1", retryAfter(t, h, "10.0.0.1", testEmail))
+}
+
+func TestAttemptsPerAction(t *testing.T) {
+	h, _ := newAttemptsHandler()
+
+	for i := 0; i < delayAfterAttempts; i++ {
+		failAttempt(t, h, "10.0.0.1", testEmail)
+	}
+
+	c, _ := newAttemptContext("10.0.0.1")
+	require.True(t, h.checkAttempts(c, twoFactorAction, testEmail))
+}
@@ -0,0 +1,130 @@
+package v1
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mirasildev/note_project/api/models"
+	"github.com/mirasildev/note_project/storage/repo"
+)
+
+// audit records an event of the user, or of the email if the user is
+// unknown, with the client of the request. A failure to record doesn't
+// fail the request, it is logged instead.
+func (h *handlerV1) audit(c *gin.Context, event string, userID int64, email string) {
+	l := &repo.AuditLog{
+		Email:     email,
+		Event:     event,
+		IPAddress: c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+	if userID != 0 {
+		l.UserID = &userID
+	}
+
+	_, err := h.storage.AuditLog().Create(l)
+	if err != nil {
+		log.Printf("failed to record %s audit event: %v", event, err)
+	}
+}
+
+// @Security ApiKeyAuth
+// @Router /audit-logs [get]
+// @Summary Get audit logs
+// @Description Get the authentication events, newest first. Only for admins
+// @Tags audit-logs
+// @Accept json
+// @Produce json
+// @Param filter query models.GetAllAuditLogsParams false "Filter"
+// @Success 200 {object} models.GetAllAuditLogsResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) GetAuditLogs(c *gin.Context) {
+	req, err := validateGetAllAuditLogsParams(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	result, err := h.storage.AuditLog().GetAll(&repo.GetAllAuditLogsParams{
+		Limit:  req.Limit,
+		Page:   req.Page,
+		UserID: req.UserID,
+		Event:  req.Event,
+		From:   req.From,
+		To:     req.To,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	response := models.GetAllAuditLogsResponse{
+		AuditLogs: make([]*models.AuditLog, 0),
+		Count:     result.Count,
+	}
+	for _, l := range result.AuditLogs {
+		response.AuditLogs = append(response.AuditLogs, &models.AuditLog{
+			ID:        l.ID,
+			UserID:    l.UserID,
+			Email:     l.Email,
+			Event:     l.Event,
+			IPAddress: l.IPAddress,
+			UserAgent: l.UserAgent,
+			CreatedAt: l.CreatedAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func validateGetAllAuditLogsParams(c *gin.Context) (*models.GetAllAuditLogsParams, error) {
+	params, err := validateGetAllParams(c)
+	if err != nil {
+		return nil, err
+	}
+
+	result := models.GetAllAuditLogsParams{
+		Limit: params.Limit,
+		Page:  params.Page,
+		Event: c.Query("event"),
+	}
+
+	if c.Query("user_id") != "" {
+		userID, err := strconv.ParseInt(c.Query("user_id"), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		result.UserID = &userID
+	}
+
+	result.From, err = parseTimeQuery(c, "from")
+	if err != nil {
+		return nil, err
+	}
+
+	result.To, err = parseTimeQuery(c, "to")
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// parseTimeQuery parses an optional RFC 3339 query parameter
+func parseTimeQuery(c *gin.Context, name string) (*time.Time, error) {
+	if c.Query(name) == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, c.Query(name))
+	if err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
@@ -5,9 +5,9 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
-	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/mirasildev/note_project/api/models"
@@ -24,8 +24,11 @@ var (
 )
 
 const (
-	RegisterCode      = "register_code_"
-	ForgotPasswordKey = "forgot_password_code_"
+	PendingUserKey       = "user_"
+	RegisterCode         = "register_code_"
+	ForgotPasswordKey    = "forgot_password_code_"
+	UserTokensRevokedKey = "user_tokens_revoked_at_"
+	RevokedTokenKey      = "revoked_token_"
 )
 
 // @Router /auth/register [post]
@@ -36,6 +39,7 @@ const (
 // @Produce json
 // @Param data body models.RegisterRequest true "Data"
 // @Success 200 {object} models.ResponseOK
+// @Failure 409 {object} models.VerificationErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 func (h *handlerV1) Register(c *gin.Context) {
 	var req models.RegisterRequest
@@ -46,13 +50,17 @@ func (h *handlerV1) Register(c *gin.Context) {
 		return
 	}
 
+	if !h.validatePassword(c, req.Password) {
+		return
+	}
+
 	_, err = h.storage.User().GetByEmail(req.Email)
 	if !errors.Is(err, sql.ErrNoRows) {
 		c.JSON(http.StatusBadRequest, errorResponse(ErrEmailExists))
 		return
 	}
 
-	hashedPassword, err := utils.HashPassword(req.Password)
+	hashedPassword, err := utils.HashPassword(h.cfg, req.Password)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
@@ -62,6 +70,7 @@ func (h *handlerV1) Register(c *gin.Context) {
 		LastName:  req.LastName,
 		Email:     req.Email,
 		Password:  hashedPassword,
+		Role:      repo.UserRoleUser,
 	}
 
 	userData, err := json.Marshal(user)
@@ -70,18 +79,33 @@ func (h *handlerV1) Register(c *gin.Context) {
 		return
 	}
 
-	err = h.inMemory.Set("user_"+user.Email, string(userData), 10*time.Minute)
+	created, err := h.inMemory.SetNX(PendingUserKey+user.Email, string(userData), registrationTTL)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	go func() {
-		err := h.sendVerificationCode(RegisterCode, req.Email)
-		if err != nil {
-			fmt.Printf("Failed to send verification code: %v", err)
-		}
-	}()
+	if !created {
+		c.JSON(http.StatusConflict, models.VerificationErrorResponse{
+			Error:  ErrRegistrationPending.Error(),
+			Status: RegistrationStatusPending,
+		})
+		return
+	}
+
+	err = h.inMemory.Set(ResendCodeCooldownKey+req.Email, "1", resendCodeCooldown)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = h.startVerification(req.Email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	h.audit(c, repo.AuditEventRegister, 0, req.Email)
 
 	c.JSON(http.StatusCreated, models.ResponseOK{
 		Message: "Verification code has been sent!",
@@ -89,24 +113,29 @@ func (h *handlerV1) Register(c *gin.Context) {
 
 }
 
-func (h *handlerV1) sendVerificationCode(key, email string) error {
+func (h *handlerV1) sendVerificationCode(key, email, emailType string) error {
 	code, err := utils.GenerateRandomCode(6)
 	if err != nil {
 		return err
 	}
 
-	err = h.inMemory.Set(key+email, code, time.Minute)
+	err = h.inMemory.Set(key+email, code, verificationCodeTTL)
 	if err != nil {
 		return err
 	}
 
+	subject := "Verification email"
+	if emailType == emailPkg.ForgotPasswordEmail {
+		subject = "Reset your password"
+	}
+
 	err = emailPkg.SendEmail(h.cfg, &emailPkg.SendEmailRequest{
 		To:      []string{email},
-		Subject: "Verification email",
+		Subject: subject,
 		Body: map[string]string{
 			"code": code,
 		},
-		Type: emailPkg.VerificationEmail,
+		Type: emailType,
 	})
 	if err != nil {
 		return err
@@ -122,7 +151,10 @@ func (h *handlerV1) sendVerificationCode(key, email string) error {
 // @Accept json
 // @Produce json
 // @Param data body models.VerifyRequest true "Data"
-// @Success 200 {object} models.AuthResponse
+// @Success 201 {object} models.AuthResponse
+// @Failure 403 {object} models.VerificationErrorResponse
+// @Failure 404 {object} models.VerificationErrorResponse
+// @Failure 429 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 func (h *handlerV1) Verify(c *gin.Context) {
 	var req models.VerifyRequest
@@ -133,57 +165,79 @@ func (h *handlerV1) Verify(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, errorResponse(err))
 		return
 	}
-	userData, err := h.inMemory.Get("user_" + req.Email)
-	if err != nil {
-		// log.Print(err)
-		c.JSON(http.StatusBadRequest, errorResponse(err))
+
+	if !h.checkAttempts(c, verifyAction, req.Email) {
 		return
 	}
 
-	var user repo.User
-	err = json.Unmarshal([]byte(userData), &user)
+	user, check, err := h.checkRegistrationCode(req.Email, req.Code)
 	if err != nil {
-		log.Print(err)
-		c.JSON(http.StatusForbidden, errorResponse(err))
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	code, err := h.inMemory.Get(RegisterCode + user.Email)
+	switch check.Status {
+	case RegistrationStatusVerified:
+	case RegistrationStatusNotFound:
+		c.JSON(http.StatusNotFound, models.VerificationErrorResponse{
+			Error:  ErrRegistrationNotFound.Error(),
+			Status: check.Status,
+		})
+		return
+	case RegistrationStatusCodeExpired:
+		c.JSON(http.StatusForbidden, models.VerificationErrorResponse{
+			Error:  ErrCodeExpired.Error(),
+			Status: check.Status,
+		})
+		return
+	case RegistrationStatusTooManyAttempts:
+		c.JSON(http.StatusForbidden, models.VerificationErrorResponse{
+			Error:  ErrTooManyCodeAttempts.Error(),
+			Status: check.Status,
+		})
+		return
+	case RegistrationStatusCodeIncorrect:
+		err = h.registerFailedAttempt(c, verifyAction, req.Email)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		c.JSON(http.StatusForbidden, models.VerificationErrorResponse{
+			Error:        ErrIncorrectCode.Error(),
+			Status:       check.Status,
+			AttemptsLeft: check.AttemptsLeft,
+		})
+		return
+	}
+
+	err = h.resetAttempts(verifyAction, req.Email)
 	if err != nil {
-		log.Print(err)
-		c.JSON(http.StatusForbidden, errorResponse(ErrCodeExpired))
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	if req.Code != code {
-		// log.Print(err)
-		c.JSON(http.StatusForbidden, errorResponse(ErrIncorrectCode))
+	result, err := h.storage.User().Create(user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	result, err := h.storage.User().Create(&user)
+	h.audit(c, repo.AuditEventVerify, result.ID, result.Email)
+
+	err = h.finishVerification(req.Email)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
-	token, _, err := utils.CreateToken(h.cfg, &utils.TokenParams{
-		UserID:   result.ID,
-		Email:    result.Email,
-		Duration: time.Hour * 24,
-	})
+
+	resp, err := h.newAuthResponse(c, result)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	c.JSON(http.StatusCreated, models.AuthResponse{
-		ID:          user.ID,
-		FirstName:   user.FirstName,
-		LastName:    user.LastName,
-		Email:       user.Email,
-		CreatedAt:   user.CreatedAt,
-		AccessToken: token,
-	})
+	c.JSON(http.StatusCreated, resp)
 }
 
 // @Router /auth/login [post]
@@ -193,7 +247,9 @@ func (h *handlerV1) Verify(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param data body models.LoginRequest true "Data"
-// @Success 200 {object} models.AuthResponse
+// @Success 200 {object} models.TwoFactorChallengeResponse "Two-factor authentication is enabled"
+// @Success 201 {object} models.AuthResponse
+// @Failure 429 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 func (h *handlerV1) Login(c *gin.Context) {
 	var req models.LoginRequest
@@ -204,11 +260,221 @@ func (h *handlerV1) Login(c *gin.Context) {
 		return
 	}
 
+	if !h.checkAttempts(c, loginAction, req.Email) {
+		return
+	}
+
 	result, err := h.storage.User().GetByEmail(req.Email)
 	if err != nil {
-		log.Println("Hello World 1")
 		if errors.Is(err, sql.ErrNoRows) {
-			c.JSON(http.StatusForbidden, errorResponse(ErrWrongEmailOrPass))
+			h.failLogin(c, 0, req.Email)
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rehashed, err := utils.CheckPassword(h.cfg, req.Password, result.Password)
+	if err != nil {
+		h.failLogin(c, result.ID, req.Email)
+		return
+	}
+
+	// The password is stored with outdated hashing, which is fixed
+	// while the plain password is known
+	if rehashed != "" {
+		err = h.storage.User().UpdatePassword(result.ID, rehashed)
+		if err != nil {
+			log.Printf("failed to rehash password of user %d: %v", result.ID, err)
+		}
+	}
+
+	err = h.resetAttempts(loginAction, req.Email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	h.completeLogin(c, result)
+}
+
+// completeLogin responds with a two factor challenge if the user
+// has it enabled and with new tokens otherwise
+func (h *handlerV1) completeLogin(c *gin.Context, user *repo.User) {
+	if user.DeletionScheduledAt != nil {
+		c.JSON(http.StatusForbidden, errorResponse(ErrAccountDeletionScheduled))
+		return
+	}
+
+	twoFactor, err := h.storage.TwoFactor().Get(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if twoFactor.Enabled {
+		challenge, err := h.newTwoFactorChallenge(user.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		c.JSON(http.StatusOK, challenge)
+		return
+	}
+
+	resp, err := h.newAuthResponse(c, user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	h.audit(c, repo.AuditEventLoginSucceeded, user.ID, user.Email)
+
+	c.JSON(http.StatusCreated, resp)
+}
+
+// failLogin counts a failed login of the email, userID is 0 if no user has it
+func (h *handlerV1) failLogin(c *gin.Context, userID int64, email string) {
+	h.audit(c, repo.AuditEventLoginFailed, userID, email)
+
+	err := h.registerFailedAttempt(c, loginAction, email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusForbidden, errorResponse(ErrWrongEmailOrPass))
+}
+
+// @Router /auth/refresh [post]
+// @Summary Refresh tokens
+// @Description Exchange a refresh token for a new access and refresh token
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param data body models.RefreshTokenRequest true "Data"
+// @Success 200 {object} models.AuthResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) Refresh(c *gin.Context) {
+	var req models.RefreshTokenRequest
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	resp, err := h.rotateRefreshToken(req.RefreshToken, "")
+	if err != nil {
+		if errors.Is(err, ErrInvalidRefreshToken) || errors.Is(err, ErrRefreshTokenReused) ||
+			errors.Is(err, ErrTokenRevoked) || errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusUnauthorized, errorResponse(err))
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// @Router /auth/forgot-password [post]
+// @Summary Forgot password
+// @Description Send a password reset code to the user's email
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param data body models.ForgotPasswordRequest true "Data"
+// @Success 200 {object} models.ResponseOK
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) ForgotPassword(c *gin.Context) {
+	var req models.ForgotPasswordRequest
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	_, err = h.storage.User().GetByEmail(req.Email)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// Respond the same way for unknown emails so the endpoint
+	// can't be used to find out who is registered.
+	if err == nil {
+		go func() {
+			err := h.sendVerificationCode(ForgotPasswordKey, req.Email, emailPkg.ForgotPasswordEmail)
+			if err != nil {
+				fmt.Printf("Failed to send reset password code: %v", err)
+			}
+		}()
+	}
+
+	c.JSON(http.StatusOK, models.ResponseOK{
+		Message: "If the email is registered, a reset code has been sent!",
+	})
+}
+
+// @Router /auth/reset-password [post]
+// @Summary Reset password
+// @Description Set a new password using the code sent by forgot-password
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param data body models.ResetPasswordRequest true "Data"
+// @Success 200 {object} models.ResponseOK
+// @Failure 429 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) ResetPassword(c *gin.Context) {
+	var req models.ResetPasswordRequest
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if !h.validatePassword(c, req.Password) {
+		return
+	}
+
+	if !h.checkAttempts(c, verifyAction, req.Email) {
+		return
+	}
+
+	code, err := h.inMemory.Get(ForgotPasswordKey + req.Email)
+	if err != nil {
+		c.JSON(http.StatusForbidden, errorResponse(ErrCodeExpired))
+		return
+	}
+
+	if req.Code != code {
+		err = h.registerFailedAttempt(c, verifyAction, req.Email)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		c.JSON(http.StatusForbidden, errorResponse(ErrIncorrectCode))
+		return
+	}
+
+	err = h.resetAttempts(verifyAction, req.Email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	user, err := h.storage.User().GetByEmail(req.Email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, errorResponse(err))
 			return
 		}
 
@@ -216,29 +482,114 @@ func (h *handlerV1) Login(c *gin.Context) {
 		return
 	}
 
-	err = utils.CheckPassword(req.Password, result.Password)
+	hashedPassword, err := utils.HashPassword(h.cfg, req.Password)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = h.storage.User().UpdatePassword(user.ID, hashedPassword)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = h.inMemory.Delete(ForgotPasswordKey + req.Email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = h.revokeUserTokens(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = h.storage.PersonalAccessToken().DeleteAll(user.ID)
 	if err != nil {
-		log.Println("Hello World 1")
-		c.JSON(http.StatusForbidden, errorResponse(ErrWrongEmailOrPass))
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	token, _, err := utils.CreateToken(h.cfg, &utils.TokenParams{
-		UserID: result.ID,
-		Email: result.Email,
-		Duration: time.Hour * 24,
+	h.audit(c, repo.AuditEventPasswordChanged, user.ID, user.Email)
+
+	c.JSON(http.StatusOK, models.ResponseOK{
+		Message: "Password has been reset!",
 	})
+}
+
+// @Security ApiKeyAuth
+// @Router /auth/logout [post]
+// @Summary Logout user
+// @Description Revoke the current access token and, if given, its refresh token
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param data body models.LogoutRequest false "Data"
+// @Success 200 {object} models.ResponseOK
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) Logout(c *gin.Context) {
+	var req models.LogoutRequest
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := h.GetAuthPayload(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	err = h.revokeAccessToken(payload)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	c.JSON(http.StatusCreated, models.AuthResponse{
-		ID:          result.ID,
-		FirstName:   result.FirstName,
-		LastName:    result.LastName,
-		Email:       result.Email,
-		CreatedAt:   result.CreatedAt,
-		AccessToken: token,
+	if req.RefreshToken != "" {
+		err = h.revokeRefreshToken(payload.UserID, req.RefreshToken)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+	}
+
+	h.audit(c, repo.AuditEventTokenRevoked, payload.UserID, payload.Email)
+
+	c.JSON(http.StatusOK, models.ResponseOK{
+		Message: "Successfully logged out!",
+	})
+}
+
+// @Security ApiKeyAuth
+// @Router /auth/logout-all [post]
+// @Summary Logout from all devices
+// @Description Revoke every access and refresh token issued to the user
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Success 200 {object} models.ResponseOK
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) LogoutAll(c *gin.Context) {
+	payload, err := h.GetAuthPayload(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	err = h.revokeUserTokens(payload.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	h.audit(c, repo.AuditEventTokenRevoked, payload.UserID, payload.Email)
+
+	c.JSON(http.StatusOK, models.ResponseOK{
+		Message: "Successfully logged out from all devices!",
 	})
 }
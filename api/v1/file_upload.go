@@ -9,6 +9,8 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+
+	"github.com/mirasildev/note_project/storage/repo"
 )
 
 type File struct {
@@ -49,6 +51,23 @@ func (h *handlerV1) UploadFile(c *gin.Context) {
 		return
 	}
 
+	payload, err := h.GetAuthPayload(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	// Files are recorded so they can be removed with the account of the owner
+	_, err = h.storage.File().Create(&repo.File{
+		ID:     id.String(),
+		UserID: payload.UserID,
+		Path:   filePath,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"file name": filePath,
 	})
@@ -6,6 +6,8 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/mirasildev/note_project/api/models"
 	"github.com/mirasildev/note_project/config"
+	"github.com/mirasildev/note_project/pkg/oidc"
+	"github.com/mirasildev/note_project/pkg/webauthn"
 	"github.com/mirasildev/note_project/storage"
 )
 
@@ -13,6 +15,8 @@ type handlerV1 struct {
 	cfg     *config.Config
 	storage storage.StorageI
 	inMemory storage.InMemoryStorageI
+	oidc     *oidc.Providers
+	webauthn *webauthn.WebAuthn
 }
 
 type HandlerV1Options struct {
@@ -26,6 +30,8 @@ func New(options *HandlerV1Options) *handlerV1 {
 		cfg:     options.Cfg,
 		storage: options.Storage,
 		inMemory: options.InMemory,
+		oidc:     oidc.NewProviders(options.Cfg.OidcProviders),
+		webauthn: webauthn.New(options.Cfg.WebAuthn),
 	}
 }
 
@@ -0,0 +1,231 @@
+package v1
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mirasildev/note_project/config"
+	"github.com/mirasildev/note_project/pkg/utils"
+	"github.com/mirasildev/note_project/storage"
+	"github.com/mirasildev/note_project/storage/repo"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// fakeStorage serves the repositories a test sets, calling any other panics
+type fakeStorage struct {
+	storage.StorageI
+	notes         repo.NoteStorageI
+	users         repo.UserStorageI
+	noteRevisions repo.NoteRevisionStorageI
+	sessions      repo.SessionStorageI
+	twoFactor     repo.TwoFactorStorageI
+	auditLogs     repo.AuditLogStorageI
+	tags          repo.TagStorageI
+}
+
+func (s *fakeStorage) Note() repo.NoteStorageI {
+	return s.notes
+}
+
+func (s *fakeStorage) User() repo.UserStorageI {
+	return s.users
+}
+
+func (s *fakeStorage) NoteRevision() repo.NoteRevisionStorageI {
+	return s.noteRevisions
+}
+
+func (s *fakeStorage) Session() repo.SessionStorageI {
+	return s.sessions
+}
+
+func (s *fakeStorage) TwoFactor() repo.TwoFactorStorageI {
+	return s.twoFactor
+}
+
+func (s *fakeStorage) AuditLog() repo.AuditLogStorageI {
+	return s.auditLogs
+}
+
+func (s *fakeStorage) Tag() repo.TagStorageI {
+	return s.tags
+}
+
+// fakeAuditLogs drops the events
+type fakeAuditLogs struct {
+	repo.AuditLogStorageI
+}
+
+func (l *fakeAuditLogs) Create(log *repo.AuditLog) (*repo.AuditLog, error) {
+	return log, nil
+}
+
+// fakeUsers knows every user by id and no user by email
+type fakeUsers struct {
+	repo.UserStorageI
+}
+
+func (u *fakeUsers) Get(id int64) (*repo.User, error) {
+	return &repo.User{ID: id}, nil
+}
+
+func (u *fakeUsers) GetByEmail(email string) (*repo.User, error) {
+	return nil, sql.ErrNoRows
+}
+
+type fakeItem struct {
+	value     string
+	expiresAt time.Time
+}
+
+// fakeInMemory behaves like redis with a clock the test moves forward.
+// Every call yields first, so concurrent requests interleave like over the network.
+type fakeInMemory struct {
+	mu    sync.Mutex
+	now   time.Time
+	items map[string]fakeItem
+}
+
+func newFakeInMemory() *fakeInMemory {
+	return &fakeInMemory{
+		now:   time.Now(),
+		items: make(map[string]fakeItem),
+	}
+}
+
+func (m *fakeInMemory) advance(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.now = m.now.Add(d)
+}
+
+func (m *fakeInMemory) get(key string) (fakeItem, bool) {
+	item, ok := m.items[key]
+	if ok && !item.expiresAt.IsZero() && !m.now.Before(item.expiresAt) {
+		delete(m.items, key)
+		return fakeItem{}, false
+	}
+
+	return item, ok
+}
+
+func (m *fakeInMemory) set(key, value string, exp time.Duration) {
+	item := fakeItem{value: value}
+	if exp > 0 {
+		item.expiresAt = m.now.Add(exp)
+	}
+
+	m.items[key] = item
+}
+
+func (m *fakeInMemory) Set(key, value string, exp time.Duration) error {
+	runtime.Gosched()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.set(key, value, exp)
+	return nil
+}
+
+func (m *fakeInMemory) Get(key string) (string, error) {
+	runtime.Gosched()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	item, ok := m.get(key)
+	if !ok {
+		return "", storage.ErrKeyNotFound
+	}
+
+	return item.value, nil
+}
+
+func (m *fakeInMemory) Delete(key string) error {
+	runtime.Gosched()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.items, key)
+	return nil
+}
+
+func (m *fakeInMemory) SetNX(key, value string, exp time.Duration) (bool, error) {
+	runtime.Gosched()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.get(key); ok {
+		return false, nil
+	}
+
+	m.set(key, value, exp)
+	return true, nil
+}
+
+func (m *fakeInMemory) Incr(key string, exp time.Duration) (int64, error) {
+	runtime.Gosched()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	item, ok := m.get(key)
+	if !ok {
+		m.set(key, "1", exp)
+		return 1, nil
+	}
+
+	val, err := strconv.ParseInt(item.value, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	item.value = strconv.FormatInt(val+1, 10)
+	m.items[key] = item
+
+	return val + 1, nil
+}
+
+func (m *fakeInMemory) TTL(key string) (time.Duration, error) {
+	runtime.Gosched()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	item, ok := m.get(key)
+	if !ok || item.expiresAt.IsZero() {
+		return 0, nil
+	}
+
+	return item.expiresAt.Sub(m.now), nil
+}
+
+func newTestHandler(strg *fakeStorage) *handlerV1 {
+	return New(&HandlerV1Options{
+		Cfg:      &config.Config{},
+		Storage:  strg,
+		InMemory: newFakeInMemory(),
+	})
+}
+
+// authorized runs the handler as if the user had passed the auth middleware
+func authorized(payload *utils.Payload, handler gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(authorizationPayloadKey, payload)
+		handler(c)
+	}
+}
+
+func performRequest(router *gin.Engine, req *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
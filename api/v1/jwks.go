@@ -0,0 +1,27 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mirasildev/note_project/pkg/utils"
+)
+
+// @Router /.well-known/jwks.json [get]
+// @Summary Get JSON Web Key Set
+// @Description Public keys which access tokens are verified with. Empty when tokens are signed with HS256
+// @Tags auth
+// @Produce json
+// @Success 200 {object} utils.JWKSet
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) GetJWKS(c *gin.Context) {
+	keySet, err := utils.LoadKeySet(h.cfg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, keySet.JWKS())
+}
@@ -0,0 +1,210 @@
+package v1
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mirasildev/note_project/api/models"
+	emailPkg "github.com/mirasildev/note_project/pkg/email"
+	"github.com/mirasildev/note_project/pkg/utils"
+	"github.com/mirasildev/note_project/storage"
+)
+
+const (
+	MagicLinkKey         = "magic_link_"
+	MagicLinkUsedKey     = "magic_link_used_"
+	MagicLinkCooldownKey = "magic_link_cooldown_"
+)
+
+const (
+	magicLinkTTL      = 10 * time.Minute
+	magicLinkCooldown = time.Minute
+)
+
+var (
+	ErrInvalidMagicLink = errors.New("sign in link is invalid or has already been used")
+	ErrMagicLinkExpired = errors.New("sign in link has expired, request a new one")
+	ErrMagicLinkTooSoon = errors.New("sign in link was sent recently, try again later")
+)
+
+// @Router /auth/magic-link [post]
+// @Summary Send a magic link
+// @Description Email a single-use link to sign in without a password
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param data body models.MagicLinkRequest true "Data"
+// @Success 200 {object} models.ResponseOK
+// @Failure 429 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) SendMagicLink(c *gin.Context) {
+	var req models.MagicLinkRequest
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	firstRequest, err := h.inMemory.SetNX(MagicLinkCooldownKey+req.Email, "1", magicLinkCooldown)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if !firstRequest {
+		ttl, err := h.inMemory.TTL(MagicLinkCooldownKey + req.Email)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(ttl.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, errorResponse(ErrMagicLinkTooSoon))
+		return
+	}
+
+	_, err = h.storage.User().GetByEmail(req.Email)
+	if err == nil {
+		go func() {
+			err := h.sendMagicLink(req.Email)
+			if err != nil {
+				fmt.Printf("Failed to send magic link: %v", err)
+			}
+		}()
+	}
+
+	// Respond the same way for unknown emails so the endpoint
+	// can't be used to find out who is registered.
+	c.JSON(http.StatusOK, models.ResponseOK{
+		Message: "If the email is registered, a sign in link has been sent!",
+	})
+}
+
+func (h *handlerV1) sendMagicLink(email string) error {
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return err
+	}
+
+	err = h.inMemory.Set(MagicLinkKey+utils.HashToken(token), email, magicLinkTTL)
+	if err != nil {
+		return err
+	}
+
+	link, err := url.Parse(h.cfg.MagicLinkURL)
+	if err != nil {
+		return err
+	}
+
+	expires := strconv.FormatInt(time.Now().Add(magicLinkTTL).Unix(), 10)
+	link.RawQuery = url.Values{
+		"email":     {email},
+		"token":     {token},
+		"expires":   {expires},
+		"signature": {utils.Sign(h.cfg.AuthSecretKey, email, token, expires)},
+	}.Encode()
+
+	return emailPkg.SendEmail(h.cfg, &emailPkg.SendEmailRequest{
+		To:      []string{email},
+		Subject: "Your sign in link",
+		Body: map[string]string{
+			"link":     link.String(),
+			"duration": magicLinkTTL.String(),
+		},
+		Type: emailPkg.MagicLinkEmail,
+	})
+}
+
+// @Router /auth/magic-link/callback [get]
+// @Summary Sign in with a magic link
+// @Description Exchange the link from the email for tokens. The response is the same as of login
+// @Tags auth
+// @Produce json
+// @Param email query string true "Email"
+// @Param token query string true "Token"
+// @Param expires query string true "Expires"
+// @Param signature query string true "Signature"
+// @Success 201 {object} models.AuthResponse
+// @Success 200 {object} models.TwoFactorChallengeResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) MagicLinkCallback(c *gin.Context) {
+	email := c.Query("email")
+	token := c.Query("token")
+	expires := c.Query("expires")
+
+	if email == "" || token == "" ||
+		!utils.VerifySignature(h.cfg.AuthSecretKey, c.Query("signature"), email, token, expires) {
+		c.JSON(http.StatusUnauthorized, errorResponse(ErrInvalidMagicLink))
+		return
+	}
+
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, errorResponse(ErrInvalidMagicLink))
+		return
+	}
+
+	if time.Now().Unix() > expiresAt {
+		c.JSON(http.StatusUnauthorized, errorResponse(ErrMagicLinkExpired))
+		return
+	}
+
+	tokenHash := utils.HashToken(token)
+
+	firstUse, err := h.inMemory.SetNX(MagicLinkUsedKey+tokenHash, "1", magicLinkTTL)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if !firstUse {
+		c.JSON(http.StatusUnauthorized, errorResponse(ErrInvalidMagicLink))
+		return
+	}
+
+	sentTo, err := h.inMemory.Get(MagicLinkKey + tokenHash)
+	if err != nil {
+		if errors.Is(err, storage.ErrKeyNotFound) {
+			c.JSON(http.StatusUnauthorized, errorResponse(ErrInvalidMagicLink))
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = h.inMemory.Delete(MagicLinkKey + tokenHash)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if sentTo != email {
+		c.JSON(http.StatusUnauthorized, errorResponse(ErrInvalidMagicLink))
+		return
+	}
+
+	user, err := h.storage.User().GetByEmail(email)
+	if err != nil {
+		// the account was purged or its email changed since the link was sent
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusUnauthorized, errorResponse(ErrInvalidMagicLink))
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	h.completeLogin(c, user)
+}
@@ -0,0 +1,38 @@
+package v1
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mirasildev/note_project/pkg/utils"
+)
+
+func TestMagicLinkOfPurgedUser(t *testing.T) {
+	// fakeUsers has no user with the email, like after the account was purged
+	h := newTestHandler(&fakeStorage{users: &fakeUsers{}})
+	h.cfg.AuthSecretKey = "secret"
+
+	router := gin.New()
+	router.GET("/auth/magic-link/callback", h.MagicLinkCallback)
+
+	token := "magic-token"
+	require.NoError(t, h.inMemory.Set(MagicLinkKey+utils.HashToken(token), testEmail, magicLinkTTL))
+
+	expires := strconv.FormatInt(time.Now().Add(magicLinkTTL).Unix(), 10)
+	query := url.Values{
+		"email":     {testEmail},
+		"token":     {token},
+		"expires":   {expires},
+		"signature": {utils.Sign(h.cfg.AuthSecretKey, testEmail, token, expires)},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/auth/magic-link/callback?"+query.Encode(), nil)
+	requireError(t, performRequest(router, req), http.StatusUnauthorized, ErrInvalidMagicLink)
+}
@@ -3,9 +3,13 @@ package v1
 import (
 	"errors"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/mirasildev/note_project/pkg/utils"
+	"github.com/mirasildev/note_project/storage"
 )
 
 
@@ -14,7 +18,28 @@ const (
 	authorizationPayloadKey = "authorization_payload"
 )
 
+var (
+	ErrTokenRevoked = errors.New("token has been revoked")
+	ErrForbidden    = errors.New("you don't have permission to access this resource")
+
+	ErrInsufficientScope = errors.New("token does not have the required scope")
+)
+
+// AuthMiddleware accepts access tokens with full access only.
+// Scoped tokens are rejected unless the route uses ScopedAuthMiddleware.
 func (h *handlerV1) AuthMiddleware(c *gin.Context) {
+	h.authenticate(c)
+}
+
+// ScopedAuthMiddleware also accepts scoped tokens, such as personal
+// access tokens, as long as they grant all of the given scopes.
+func (h *handlerV1) ScopedAuthMiddleware(scopes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h.authenticate(c, scopes...)
+	}
+}
+
+func (h *handlerV1) authenticate(c *gin.Context, scopes ...string) {
 	accessToken := c.GetHeader(authorizationHeaderKey)
 
 	if len(accessToken) == 0 {
@@ -23,9 +48,20 @@ func (h *handlerV1) AuthMiddleware(c *gin.Context) {
 		return
 	}
 
-	payload, err := utils.VerifyToken(h.cfg, accessToken)
+	payload, err := h.verifyAccessToken(accessToken)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+		if errors.Is(err, utils.ErrInvalidToken) || errors.Is(err, utils.ErrExpiredToken) ||
+			errors.Is(err, ErrTokenRevoked) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+			return
+		}
+
+		c.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if len(payload.Scopes) > 0 && (len(scopes) == 0 || !payload.HasScopes(scopes...)) {
+		c.AbortWithStatusJSON(http.StatusForbidden, errorResponse(ErrInsufficientScope))
 		return
 	}
 
@@ -33,6 +69,57 @@ func (h *handlerV1) AuthMiddleware(c *gin.Context) {
 	c.Next()
 }
 
+// verifyAccessToken accepts both JWT access tokens and personal access tokens
+func (h *handlerV1) verifyAccessToken(accessToken string) (*utils.Payload, error) {
+	if strings.HasPrefix(accessToken, personalAccessTokenPrefix) {
+		return h.verifyPersonalAccessToken(accessToken)
+	}
+
+	payload, err := utils.VerifyToken(h.cfg, accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	revoked, err := h.isAccessTokenRevoked(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if revoked {
+		return nil, ErrTokenRevoked
+	}
+
+	if payload.SessionID != "" {
+		err = h.checkSession(payload.SessionID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return payload, nil
+}
+
+// RoleMiddleware lets the request through only if the authenticated user
+// has one of the given roles. It must be used after AuthMiddleware.
+func (h *handlerV1) RoleMiddleware(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		payload, err := h.GetAuthPayload(c)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+			return
+		}
+
+		for _, role := range roles {
+			if payload.Role == role {
+				c.Next()
+				return
+			}
+		}
+
+		c.AbortWithStatusJSON(http.StatusForbidden, errorResponse(ErrForbidden))
+	}
+}
+
 func (h *handlerV1) GetAuthPayload(c *gin.Context) (*utils.Payload, error) {
 	i, exists := c.Get(authorizationPayloadKey)
 	if !exists {
@@ -44,4 +131,59 @@ func (h *handlerV1) GetAuthPayload(c *gin.Context) (*utils.Payload, error) {
 		return nil, errors.New("unknown user")
 	}
 	return payload, nil
-}
\ No newline at end of file
+}
+
+// revokeAccessToken puts the token id on the deny list until the token expires
+func (h *handlerV1) revokeAccessToken(payload *utils.Payload) error {
+	ttl := time.Until(payload.ExpiredAt)
+	if ttl <= 0 {
+		return nil
+	}
+
+	return h.inMemory.Set(RevokedTokenKey+payload.ID.String(), "1", ttl)
+}
+
+// isAccessTokenRevoked checks both the token deny list and the user wide revocation
+func (h *handlerV1) isAccessTokenRevoked(payload *utils.Payload) (bool, error) {
+	_, err := h.inMemory.Get(RevokedTokenKey + payload.ID.String())
+	if err == nil {
+		return true, nil
+	}
+	if !errors.Is(err, storage.ErrKeyNotFound) {
+		return false, err
+	}
+
+	return h.isTokenRevoked(payload.UserID, payload.IssuedAt)
+}
+
+// revokeUserTokens invalidates every access and refresh token issued to the user up to now
+func (h *handlerV1) revokeUserTokens(userID int64) error {
+	err := h.inMemory.Set(
+		UserTokensRevokedKey+strconv.FormatInt(userID, 10),
+		time.Now().Format(time.RFC3339Nano),
+		h.cfg.RefreshTokenDuration,
+	)
+	if err != nil {
+		return err
+	}
+
+	return h.storage.Session().RevokeAll(userID)
+}
+
+// isTokenRevoked reports whether a token issued to the user at issuedAt was revoked
+func (h *handlerV1) isTokenRevoked(userID int64, issuedAt time.Time) (bool, error) {
+	revokedAt, err := h.inMemory.Get(UserTokensRevokedKey + strconv.FormatInt(userID, 10))
+	if errors.Is(err, storage.ErrKeyNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, revokedAt)
+	if err != nil {
+		return false, err
+	}
+
+	return issuedAt.Before(t), nil
+}
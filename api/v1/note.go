@@ -1,8 +1,11 @@
 package v1
 
 import (
+	"database/sql"
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -10,6 +13,11 @@ import (
 	"github.com/mirasildev/note_project/storage/repo"
 )
 
+var (
+	ErrNoteNotFound   = errors.New("note not found")
+	ErrInvalidIfMatch = errors.New("If-Match must be an ETag of the note")
+)
+
 // @Security ApiKeyAuth
 // @Router /notes [post]
 // @Summary Create a note
@@ -35,25 +43,109 @@ func (h *handlerV1) CreateNote(c *gin.Context) {
 		return
 	}
 
+	if req.NotebookID != nil && !h.checkNotebook(c, *req.NotebookID, payload.UserID) {
+		return
+	}
+
 	resp, err := h.storage.Note().Create(&repo.Note{
 		UserID:      payload.UserID,
+		NotebookID:  req.NotebookID,
 		Title:       req.Title,
-		Description: *req.Description,
+		Description: stringValue(req.Description),
+		Tags:        normalizeTags(req.Tags),
 	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	c.JSON(http.StatusOK, models.Note{
-		ID:          resp.ID,
-		UserID:      resp.UserID,
-		Title:       resp.Title,
-		Description: &resp.Description,
-		CreatedAt:   resp.CreatedAt,
-		UpdatedAt:   &resp.UpdatedAt,
-		DeletedAt:   resp.DeletedAt,
-	})
+	respondNote(c, http.StatusOK, resp)
+}
+
+func parseNoteModel(note *repo.Note) models.Note {
+	result := models.Note{
+		ID:          note.ID,
+		UserID:      note.UserID,
+		NotebookID:  note.NotebookID,
+		Title:       note.Title,
+		Description: &note.Description,
+		CreatedAt:   note.CreatedAt,
+		UpdatedAt:   &note.UpdatedAt,
+		DeletedAt:   note.DeletedAt,
+		Version:     note.Version,
+		Tags:        note.Tags,
+	}
+
+	if result.Tags == nil {
+		result.Tags = make([]string, 0)
+	}
+
+	if note.Highlight != nil {
+		result.Highlight = &models.NoteHighlight{
+			Title:       note.Highlight.Title,
+			Description: note.Highlight.Description,
+		}
+	}
+
+	return result
+}
+
+func noteETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// ifMatchVersion returns the version of the note the client has read,
+// from the If-Match header. It is 0 when any version may be overwritten
+func ifMatchVersion(c *gin.Context) (int64, error) {
+	ifMatch := strings.TrimSpace(c.GetHeader("If-Match"))
+	if ifMatch == "" || ifMatch == "*" {
+		return 0, nil
+	}
+
+	// weak tags are accepted as well, since proxies
+	// weaken the ETag when they compress the response
+	tag := strings.TrimPrefix(ifMatch, "W/")
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, ErrInvalidIfMatch
+	}
+
+	// versions are stored as integer, a larger one can't match any note
+	version, err := strconv.ParseInt(tag[1:len(tag)-1], 10, 32)
+	if err != nil || version < 1 {
+		return 0, ErrInvalidIfMatch
+	}
+
+	return version, nil
+}
+
+// respondNote responds with the note and its version as the ETag
+func respondNote(c *gin.Context, code int, note *repo.Note) {
+	c.Header("ETag", noteETag(note.Version))
+	c.JSON(code, parseNoteModel(note))
+}
+
+// respondVersionMismatch responds with the current note when
+// the client tried to change a version it is not up to date with
+func (h *handlerV1) respondVersionMismatch(c *gin.Context, id, userID int64) {
+	note, err := h.storage.Note().Get(id, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, errorResponse(ErrNoteNotFound))
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	respondNote(c, http.StatusPreconditionFailed, note)
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
 }
 
 // @Security ApiKeyAuth
@@ -75,20 +167,32 @@ func (h *handlerV1) GetNote(c *gin.Context) {
 		return
 	}
 
-	resp, err := h.storage.Note().Get(int64(id))
+	payload, err := h.GetAuthPayload(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	resp, err := h.storage.Note().Get(int64(id), payload.UserID)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, errorResponse(ErrNoteNotFound))
+			return
+		}
+
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
 			Error: err.Error(),
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, resp)
+	respondNote(c, http.StatusOK, resp)
 }
 
+// @Security ApiKeyAuth
 // @Router /notes [get]
 // @Summary Get all notes
-// @Description Get all notes
+// @Description Get all notes of the authenticated user. With search the notes are ranked by relevance and highlighted
 // @Tags notes
 // @Accept json
 // @Produce json
@@ -102,11 +206,13 @@ func (h *handlerV1) GetAllNotes(c *gin.Context) {
 		return
 	}
 
-	result, err := h.storage.Note().GetAllNotes(&repo.GetAllNotesParams{
-		Page:   req.Page,
-		Limit:  req.Limit,
-		UserID: req.UserID,
-	})
+	payload, err := h.GetAuthPayload(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	result, err := h.storage.Note().GetAllNotes(getAllNotesParams(req, payload.UserID))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
@@ -117,10 +223,11 @@ func (h *handlerV1) GetAllNotes(c *gin.Context) {
 
 func validateGetAllNotesParams(c *gin.Context) (*models.GetAllNotesParams, error) {
 	var (
-		limit  int = 10
-		page   int = 1
-		err    error
-		userID int
+		limit      int = 10
+		page       int = 1
+		notebookID int64
+		recursive  bool
+		err        error
 	)
 
 	if c.Query("limit") != "" {
@@ -137,20 +244,57 @@ func validateGetAllNotesParams(c *gin.Context) (*models.GetAllNotesParams, error
 		}
 	}
 
-	if c.Query("user_id") != "" {
-		userID, err = strconv.Atoi(c.Query("user_id"))
+	if c.Query("notebook_id") != "" {
+		notebookID, err = strconv.ParseInt(c.Query("notebook_id"), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if c.Query("recursive") != "" {
+		recursive, err = strconv.ParseBool(c.Query("recursive"))
 		if err != nil {
 			return nil, err
 		}
 	}
 
+	tagsMatch := c.DefaultQuery("tags_match", tagsMatchAny)
+	if tagsMatch != tagsMatchAny && tagsMatch != tagsMatchAll {
+		return nil, ErrInvalidTagsMatch
+	}
+
 	return &models.GetAllNotesParams{
-		Limit:  int32(limit),
-		Page:   int32(page),
-		UserID: int64(userID),
+		Limit:      int32(limit),
+		Page:       int32(page),
+		Search:     c.Query("search"),
+		Tags:       c.Query("tags"),
+		TagsMatch:  tagsMatch,
+		NotebookID: notebookID,
+		Recursive:  recursive,
 	}, nil
 }
 
+func getAllNotesParams(req *models.GetAllNotesParams, userID int64) *repo.GetAllNotesParams {
+	params := &repo.GetAllNotesParams{
+		Page:         req.Page,
+		Limit:        req.Limit,
+		UserID:       userID,
+		Search:       req.Search,
+		TagsMatchAll: req.TagsMatch == tagsMatchAll,
+	}
+
+	if req.Tags != "" {
+		params.Tags = normalizeTags(strings.Split(req.Tags, ","))
+	}
+
+	if req.NotebookID != 0 {
+		params.NotebookID = &req.NotebookID
+		params.Recursive = req.Recursive
+	}
+
+	return params
+}
+
 func getNotesResponse(data *repo.GetAllNotesResult) *models.GetAllNotesResponse {
 	response := models.GetAllNotesResponse{
 		Notes: make([]*models.Note, 0),
@@ -158,21 +302,14 @@ func getNotesResponse(data *repo.GetAllNotesResult) *models.GetAllNotesResponse
 	}
 
 	for _, note := range data.Notes {
-		p := models.Note{
-			ID:          note.ID,
-			UserID:      note.UserID,
-			Title:       note.Title,
-			Description: &note.Description,
-			CreatedAt:   note.CreatedAt,
-			UpdatedAt:   &note.UpdatedAt,
-			DeletedAt:   note.DeletedAt,
-		}
+		p := parseNoteModel(note)
 		response.Notes = append(response.Notes, &p)
 	}
 
 	return &response
 }
 
+// @Security ApiKeyAuth
 // @Router /notes/{id} [put]
 // @Summary Update a note
 // @Description Update a note
@@ -180,70 +317,119 @@ func getNotesResponse(data *repo.GetAllNotesResult) *models.GetAllNotesResponse
 // @Accept json
 // @Produce json
 // @Param id path int true "ID"
+// @Param If-Match header string false "ETag of the note the changes are based on"
 // @Param note body models.UpdateNoteRequest true "Note"
 // @Success 200 {object} models.Note
+// @Failure 412 {object} models.Note
 // @Failure 500 {object} models.ErrorResponse
 func (h *handlerV1) UpdateNote(c *gin.Context) {
 	var req models.UpdateNoteRequest
 
 	err := c.ShouldBindJSON(&req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"message": err.Error(),
-		})
+		c.JSON(http.StatusBadRequest, errorResponse(err))
 		return
 	}
 
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"message": err.Error(),
-		})
+		c.JSON(http.StatusBadRequest, errorResponse(err))
 		return
 	}
 
-	updated, err := h.storage.Note().Update(&repo.Note{
-			ID:          id,
-			UserID:      req.UserID,
-			Title:       req.Title,
-			Description: *req.Description,
-			UpdatedAt:   time.Now(),
-	})
+	version, err := ifMatchVersion(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"message": err.Error(),
-		})
+		c.JSON(http.StatusBadRequest, errorResponse(err))
 		return
 	}
 
-	c.JSON(http.StatusOK, updated)
+	payload, err := h.GetAuthPayload(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	note := &repo.Note{
+		ID:          id,
+		UserID:      payload.UserID,
+		Title:       req.Title,
+		Description: stringValue(req.Description),
+		UpdatedAt:   time.Now(),
+		Version:     version,
+	}
+	if req.Tags != nil {
+		note.Tags = normalizeTags(*req.Tags)
+	}
+
+	updated, err := h.storage.Note().Update(note)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, errorResponse(ErrNoteNotFound))
+			return
+		}
+
+		if errors.Is(err, repo.ErrVersionMismatch) {
+			h.respondVersionMismatch(c, id, payload.UserID)
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	h.pruneNoteRevisions(id, payload.UserID)
+
+	respondNote(c, http.StatusOK, updated)
 }
 
+// @Security ApiKeyAuth
 // @Router /notes/{id} [delete]
 // @Summary Delete a note
-// @Description Delete a note
+// @Description Move a note to the trash
 // @Tags notes
 // @Accept json
 // @Produce json
 // @Param id path int true "ID"
+// @Param If-Match header string false "ETag of the note the client has read"
 // @Success 200 {object} models.ResponseOK
+// @Failure 412 {object} models.Note
 // @Failure 500 {object} models.ErrorResponse
 func (h *handlerV1) DeleteNote(ctx *gin.Context) {
 	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"message": err.Error(),
-		})
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	version, err := ifMatchVersion(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := h.GetAuthPayload(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
 		return
 	}
 
-	err = h.storage.Note().Delete(id)
+	err = h.storage.Note().Delete(id, payload.UserID, version)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errorResponse(ErrNoteNotFound))
+			return
+		}
+
+		if errors.Is(err, repo.ErrVersionMismatch) {
+			h.respondVersionMismatch(ctx, id, payload.UserID)
+			return
+		}
+
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
 	ctx.JSON(http.StatusOK, gin.H{
-		"message": "Successfully deleted!",
+		"message": "Moved to trash!",
 	})
 }
@@ -0,0 +1,335 @@
+package v1
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mirasildev/note_project/api/models"
+	"github.com/mirasildev/note_project/pkg/diff"
+	"github.com/mirasildev/note_project/storage/repo"
+)
+
+var ErrRevisionNotFound = errors.New("revision not found")
+
+func parseNoteRevisionModel(r *repo.NoteRevision) models.NoteRevision {
+	return models.NoteRevision{
+		Number:      r.Number,
+		Title:       r.Title,
+		Description: r.Description,
+		AuthorID:    r.AuthorID,
+		CreatedAt:   r.CreatedAt,
+	}
+}
+
+func parseDiffLines(lines []diff.Line) []*models.DiffLine {
+	result := make([]*models.DiffLine, 0, len(lines))
+	for _, l := range lines {
+		result = append(result, &models.DiffLine{
+			Op:   string(l.Op),
+			Text: l.Text,
+		})
+	}
+
+	return result
+}
+
+// noteRevisionLimit returns how many revisions are kept per note of the user
+func (h *handlerV1) noteRevisionLimit(userID int64) (int, error) {
+	user, err := h.storage.User().Get(userID)
+	if err != nil {
+		return 0, err
+	}
+
+	if user.NoteRevisionLimit != nil {
+		return *user.NoteRevisionLimit, nil
+	}
+
+	return h.cfg.NoteRevisionLimit, nil
+}
+
+// pruneNoteRevisions deletes the revisions of the note past the limit
+// of the user. It doesn't fail the update which wrote the revision
+func (h *handlerV1) pruneNoteRevisions(noteID, userID int64) {
+	limit, err := h.noteRevisionLimit(userID)
+	if err == nil {
+		err = h.storage.NoteRevision().Prune(noteID, limit)
+	}
+
+	if err != nil {
+		log.Printf("failed to prune revisions of note %d: %v", noteID, err)
+	}
+}
+
+// getNoteRevision responds with an error and returns nil
+// when the note has no revision with the given number
+func (h *handlerV1) getNoteRevision(c *gin.Context, noteID, userID int64, number string) *repo.NoteRevision {
+	n, err := strconv.Atoi(number)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return nil
+	}
+
+	revision, err := h.storage.NoteRevision().Get(noteID, userID, n)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, errorResponse(ErrRevisionNotFound))
+			return nil
+		}
+
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return nil
+	}
+
+	return revision
+}
+
+// @Security ApiKeyAuth
+// @Router /notes/{id}/revisions [get]
+// @Summary Get note revisions
+// @Description Get the revisions of a note, newest first
+// @Tags note-revisions
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Success 200 {object} models.GetAllNoteRevisionsResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) GetNoteRevisions(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := h.GetAuthPayload(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	revisions, err := h.storage.NoteRevision().GetAll(id, payload.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// every note has at least the revision written when it was created
+	if len(revisions) == 0 {
+		c.JSON(http.StatusNotFound, errorResponse(ErrNoteNotFound))
+		return
+	}
+
+	response := models.GetAllNoteRevisionsResponse{
+		Revisions: make([]*models.NoteRevision, 0),
+	}
+	for _, r := range revisions {
+		p := parseNoteRevisionModel(r)
+		response.Revisions = append(response.Revisions, &p)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// @Security ApiKeyAuth
+// @Router /notes/{id}/revisions/{number} [get]
+// @Summary Get a note revision
+// @Description Get a revision of a note by its number
+// @Tags note-revisions
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Param number path int true "Number"
+// @Success 200 {object} models.NoteRevision
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) GetNoteRevision(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := h.GetAuthPayload(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	revision := h.getNoteRevision(c, id, payload.UserID, c.Param("number"))
+	if revision == nil {
+		return
+	}
+
+	c.JSON(http.StatusOK, parseNoteRevisionModel(revision))
+}
+
+// @Security ApiKeyAuth
+// @Router /notes/{id}/revisions/diff [get]
+// @Summary Compare note revisions
+// @Description Show the line level changes between two revisions of a note
+// @Tags note-revisions
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Param from query int true "Number of the older revision"
+// @Param to query int true "Number of the newer revision"
+// @Success 200 {object} models.NoteRevisionDiffResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) GetNoteRevisionDiff(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := h.GetAuthPayload(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	from := h.getNoteRevision(c, id, payload.UserID, c.Query("from"))
+	if from == nil {
+		return
+	}
+
+	to := h.getNoteRevision(c, id, payload.UserID, c.Query("to"))
+	if to == nil {
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NoteRevisionDiffResponse{
+		From:        from.Number,
+		To:          to.Number,
+		Title:       parseDiffLines(diff.Lines(from.Title, to.Title)),
+		Description: parseDiffLines(diff.Lines(from.Description, to.Description)),
+	})
+}
+
+// @Security ApiKeyAuth
+// @Router /notes/{id}/revisions/{number}/restore [post]
+// @Summary Restore a note revision
+// @Description Bring back the content of a revision. The restored content is saved as a new revision
+// @Tags note-revisions
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Param number path int true "Number"
+// @Success 200 {object} models.Note
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) RestoreNoteRevision(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := h.GetAuthPayload(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	revision := h.getNoteRevision(c, id, payload.UserID, c.Param("number"))
+	if revision == nil {
+		return
+	}
+
+	updated, err := h.storage.Note().Update(&repo.Note{
+		ID:          id,
+		UserID:      payload.UserID,
+		Title:       revision.Title,
+		Description: revision.Description,
+		UpdatedAt:   time.Now(),
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, errorResponse(ErrNoteNotFound))
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	h.pruneNoteRevisions(id, payload.UserID)
+
+	respondNote(c, http.StatusOK, updated)
+}
+
+// @Security ApiKeyAuth
+// @Router /me/revision-settings [get]
+// @Summary Get revision settings
+// @Description Get how many revisions are kept per note
+// @Tags note-revisions
+// @Accept json
+// @Produce json
+// @Success 200 {object} models.RevisionSettings
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) GetRevisionSettings(c *gin.Context) {
+	payload, err := h.GetAuthPayload(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	limit, err := h.noteRevisionLimit(payload.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.RevisionSettings{
+		Limit: limit,
+	})
+}
+
+// @Security ApiKeyAuth
+// @Router /me/revision-settings [put]
+// @Summary Update revision settings
+// @Description Change how many revisions are kept per note. Revisions past a lower limit are deleted right away
+// @Tags note-revisions
+// @Accept json
+// @Produce json
+// @Param data body models.UpdateRevisionSettingsRequest true "Data"
+// @Success 200 {object} models.RevisionSettings
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) UpdateRevisionSettings(c *gin.Context) {
+	var req models.UpdateRevisionSettingsRequest
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := h.GetAuthPayload(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	err = h.storage.User().UpdateNoteRevisionLimit(payload.UserID, req.Limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	limit := h.cfg.NoteRevisionLimit
+	if req.Limit != nil {
+		limit = *req.Limit
+	}
+
+	err = h.storage.NoteRevision().PruneAll(payload.UserID, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.RevisionSettings{
+		Limit: limit,
+	})
+}
@@ -0,0 +1,212 @@
+package v1
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mirasildev/note_project/api/models"
+	"github.com/mirasildev/note_project/pkg/utils"
+	"github.com/mirasildev/note_project/storage/repo"
+)
+
+// fakeNotes keeps a single note and checks versions like the postgres repository
+type fakeNotes struct {
+	repo.NoteStorageI
+	note *repo.Note
+}
+
+func (n *fakeNotes) Get(id, userID int64) (*repo.Note, error) {
+	if id != n.note.ID || userID != n.note.UserID {
+		return nil, sql.ErrNoRows
+	}
+
+	note := *n.note
+	return &note, nil
+}
+
+func (n *fakeNotes) Update(note *repo.Note) (*repo.Note, error) {
+	current, err := n.Get(note.ID, note.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if note.Version != 0 && note.Version != current.Version {
+		return nil, repo.ErrVersionMismatch
+	}
+
+	n.note.Title = note.Title
+	n.note.Description = note.Description
+	n.note.Version++
+
+	return n.Get(note.ID, note.UserID)
+}
+
+func (n *fakeNotes) Delete(id, userID, version int64) error {
+	current, err := n.Get(id, userID)
+	if err != nil {
+		return err
+	}
+
+	if version != 0 && version != current.Version {
+		return repo.ErrVersionMismatch
+	}
+
+	n.note = &repo.Note{}
+	return nil
+}
+
+type fakeNoteRevisions struct {
+	repo.NoteRevisionStorageI
+}
+
+func (r *fakeNoteRevisions) Prune(noteID int64, keep int) error {
+	return nil
+}
+
+func newNoteRouter(notes *fakeNotes) *gin.Engine {
+	h := newTestHandler(&fakeStorage{
+		notes:         notes,
+		users:         &fakeUsers{},
+		noteRevisions: &fakeNoteRevisions{},
+	})
+	payload := &utils.Payload{UserID: notes.note.UserID}
+
+	router := gin.New()
+	router.GET("/notes/:id", authorized(payload, h.GetNote))
+	router.PUT("/notes/:id", authorized(payload, h.UpdateNote))
+	router.DELETE("/notes/:id", authorized(payload, h.DeleteNote))
+
+	return router
+}
+
+func newFakeNotes() *fakeNotes {
+	return &fakeNotes{
+		note: &repo.Note{
+			ID:      1,
+			UserID:  1,
+			Title:   "Groceries",
+			Version: 1,
+		},
+	}
+}
+
+func noteRequest(method, ifMatch string) *http.Request {
+	body := ""
+	if method == http.MethodPut {
+		body = `{"title": "Shopping list"}`
+	}
+
+	req := httptest.NewRequest(method, "/notes/1", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if ifMatch != "" {
+		req.Header.Set("If-Match", ifMatch)
+	}
+
+	return req
+}
+
+func TestNoteETag(t *testing.T) {
+	router := newNoteRouter(newFakeNotes())
+
+	resp := performRequest(router, noteRequest(http.MethodGet, ""))
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.Equal(t, `"1"`, resp.Header().Get("ETag"))
+
+	resp = performRequest(router, noteRequest(http.MethodPut, resp.Header().Get("ETag")))
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.Equal(t, `"2"`, resp.Header().Get("ETag"))
+
+	var note models.Note
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &note))
+	require.Equal(t, int64(2), note.Version)
+	require.Equal(t, "Shopping list", note.Title)
+
+	resp = performRequest(router, noteRequest(http.MethodPut, `W/"2"`))
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.Equal(t, `"3"`, resp.Header().Get("ETag"))
+}
+
+func TestNoteVersionMismatch(t *testing.T) {
+	for _, method := range []string{http.MethodPut, http.MethodDelete} {
+		t.Run(method, func(t *testing.T) {
+			notes := newFakeNotes()
+			notes.note.Version = 3
+			router := newNoteRouter(notes)
+
+			resp := performRequest(router, noteRequest(method, `"2"`))
+			require.Equal(t, http.StatusPreconditionFailed, resp.Code)
+			require.Equal(t, `"3"`, resp.Header().Get("ETag"))
+
+			var note models.Note
+			require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &note))
+			require.Equal(t, int64(3), note.Version)
+			require.Equal(t, "Groceries", note.Title)
+		})
+	}
+}
+
+func TestNoteWithoutIfMatch(t *testing.T) {
+	for _, ifMatch := range []string{"", "*"} {
+		router := newNoteRouter(newFakeNotes())
+
+		resp := performRequest(router, noteRequest(http.MethodPut, ifMatch))
+		require.Equal(t, http.StatusOK, resp.Code)
+
+		resp = performRequest(router, noteRequest(http.MethodDelete, ifMatch))
+		require.Equal(t, http.StatusOK, resp.Code)
+	}
+}
+
+func TestNoteInvalidIfMatch(t *testing.T) {
+	for _, ifMatch := range []string{
+		`1`,
+		`"`,
+		`"one"`,
+		`"0"`,
+		`"-1"`,
+		`"2147483648"`,
+		`"99999999999999999999"`,
+	} {
+		for _, method := range []string{http.MethodPut, http.MethodDelete} {
+			t.Run(fmt.Sprintf("%s %s", method, ifMatch), func(t *testing.T) {
+				notes := newFakeNotes()
+				router := newNoteRouter(notes)
+
+				resp := performRequest(router, noteRequest(method, ifMatch))
+				require.Equal(t, http.StatusBadRequest, resp.Code)
+
+				var body models.ErrorResponse
+				require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+				require.Equal(t, ErrInvalidIfMatch.Error(), body.Error)
+				require.Equal(t, int64(1), notes.note.Version)
+			})
+		}
+	}
+}
+
+func TestUpdateNoteDescriptionTooLong(t *testing.T) {
+	notes := newFakeNotes()
+	router := newNoteRouter(notes)
+
+	description := strings.Repeat("a", 100001)
+	body, err := json.Marshal(models.UpdateNoteRequest{
+		Title:       "Groceries",
+		Description: &description,
+	})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPut, "/notes/1", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp := performRequest(router, req)
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+	require.Equal(t, int64(1), notes.note.Version)
+}
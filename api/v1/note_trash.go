@@ -0,0 +1,146 @@
+package v1
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mirasildev/note_project/api/models"
+)
+
+// @Security ApiKeyAuth
+// @Router /notes/trash [get]
+// @Summary Get notes in the trash
+// @Description Get the deleted notes which can still be restored, most recently deleted first
+// @Tags notes
+// @Accept json
+// @Produce json
+// @Param filter query models.GetAllNotesParams false "Filter"
+// @Success 200 {object} models.GetAllNotesResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) GetTrash(c *gin.Context) {
+	req, err := validateGetAllNotesParams(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := h.GetAuthPayload(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	result, err := h.storage.Note().GetTrash(getAllNotesParams(req, payload.UserID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, getNotesResponse(result))
+}
+
+// @Security ApiKeyAuth
+// @Router /notes/{id}/restore [post]
+// @Summary Restore a note
+// @Description Restore a note from the trash
+// @Tags notes
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Success 200 {object} models.Note
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) RestoreNote(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := h.GetAuthPayload(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	err = h.storage.Note().Restore(id, payload.UserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, errorResponse(ErrNoteNotFound))
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	note, err := h.storage.Note().Get(id, payload.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	respondNote(c, http.StatusOK, note)
+}
+
+// @Security ApiKeyAuth
+// @Router /notes/trash/{id} [delete]
+// @Summary Delete a note permanently
+// @Description Delete a note in the trash for good
+// @Tags notes
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Success 200 {object} models.ResponseOK
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) DeleteNotePermanently(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := h.GetAuthPayload(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	err = h.storage.Note().DeletePermanently(id, payload.UserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, errorResponse(ErrNoteNotFound))
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.ResponseOK{
+		Message: "Successfully deleted!",
+	})
+}
+
+// RunTrashPurge deletes the notes kept in the trash longer than
+// NoteTrashRetention every TrashPurgeInterval until ctx is done
+func (h *handlerV1) RunTrashPurge(ctx context.Context) {
+	runPeriodically(ctx, h.cfg.TrashPurgeInterval, h.purgeTrash)
+}
+
+func (h *handlerV1) purgeTrash() {
+	count, err := h.storage.Note().PurgeTrash(time.Now().Add(-h.cfg.NoteTrashRetention))
+	if err != nil {
+		log.Printf("failed to purge trash: %v", err)
+		return
+	}
+
+	if count > 0 {
+		log.Printf("purged %d notes from the trash", count)
+	}
+}
@@ -0,0 +1,367 @@
+package v1
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mirasildev/note_project/api/models"
+	"github.com/mirasildev/note_project/storage/repo"
+)
+
+var ErrNotebookNotFound = errors.New("notebook not found")
+
+// checkNotebook responds with an error and returns false
+// when the user has no notebook with the given id
+func (h *handlerV1) checkNotebook(c *gin.Context, id, userID int64) bool {
+	_, err := h.storage.Notebook().Get(id, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, errorResponse(ErrNotebookNotFound))
+			return false
+		}
+
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return false
+	}
+
+	return true
+}
+
+func parseNotebookModel(n *repo.Notebook) models.Notebook {
+	return models.Notebook{
+		ID:        n.ID,
+		ParentID:  n.ParentID,
+		Name:      n.Name,
+		NoteCount: n.NoteCount,
+		CreatedAt: n.CreatedAt,
+		UpdatedAt: n.UpdatedAt,
+	}
+}
+
+// buildNotebookTree nests the notebooks under their parents,
+// keeping the order in which they are given
+func buildNotebookTree(notebooks []*repo.Notebook) []*models.NotebookTree {
+	nodes := make(map[int64]*models.NotebookTree, len(notebooks))
+	for _, n := range notebooks {
+		nodes[n.ID] = &models.NotebookTree{
+			Notebook: parseNotebookModel(n),
+			Children: make([]*models.NotebookTree, 0),
+		}
+	}
+
+	roots := make([]*models.NotebookTree, 0)
+	for _, n := range notebooks {
+		if n.ParentID != nil {
+			if parent, ok := nodes[*n.ParentID]; ok {
+				parent.Children = append(parent.Children, nodes[n.ID])
+				continue
+			}
+		}
+
+		roots = append(roots, nodes[n.ID])
+	}
+
+	return roots
+}
+
+// @Security ApiKeyAuth
+// @Router /notebooks [post]
+// @Summary Create a notebook
+// @Description Create a notebook at the top level or inside of another notebook
+// @Tags notebooks
+// @Accept json
+// @Produce json
+// @Param data body models.CreateNotebookRequest true "Data"
+// @Success 201 {object} models.Notebook
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) CreateNotebook(c *gin.Context) {
+	var req models.CreateNotebookRequest
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := h.GetAuthPayload(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	notebook, err := h.storage.Notebook().Create(&repo.Notebook{
+		UserID:   payload.UserID,
+		ParentID: req.ParentID,
+		Name:     req.Name,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, errorResponse(ErrNotebookNotFound))
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusCreated, parseNotebookModel(notebook))
+}
+
+// @Security ApiKeyAuth
+// @Router /notebooks/{id} [get]
+// @Summary Get notebook by id
+// @Description Get notebook by id
+// @Tags notebooks
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Success 200 {object} models.Notebook
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) GetNotebook(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := h.GetAuthPayload(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	notebook, err := h.storage.Notebook().Get(id, payload.UserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, errorResponse(ErrNotebookNotFound))
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, parseNotebookModel(notebook))
+}
+
+// @Security ApiKeyAuth
+// @Router /notebooks/tree [get]
+// @Summary Get notebook tree
+// @Description Get all notebooks of the authenticated user nested under their parents
+// @Tags notebooks
+// @Accept json
+// @Produce json
+// @Success 200 {object} models.GetNotebookTreeResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) GetNotebookTree(c *gin.Context) {
+	payload, err := h.GetAuthPayload(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	notebooks, err := h.storage.Notebook().GetAll(payload.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.GetNotebookTreeResponse{
+		Notebooks: buildNotebookTree(notebooks),
+	})
+}
+
+// @Security ApiKeyAuth
+// @Router /notebooks/{id} [put]
+// @Summary Rename a notebook
+// @Description Rename a notebook
+// @Tags notebooks
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Param data body models.RenameNotebookRequest true "Data"
+// @Success 200 {object} models.Notebook
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) RenameNotebook(c *gin.Context) {
+	var req models.RenameNotebookRequest
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := h.GetAuthPayload(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	notebook, err := h.storage.Notebook().Rename(id, payload.UserID, req.Name)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, errorResponse(ErrNotebookNotFound))
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, parseNotebookModel(notebook))
+}
+
+// @Security ApiKeyAuth
+// @Router /notebooks/{id}/move [post]
+// @Summary Move a notebook
+// @Description Move a notebook with everything inside of it into another notebook or to the top level
+// @Tags notebooks
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Param data body models.MoveNotebookRequest true "Data"
+// @Success 200 {object} models.Notebook
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) MoveNotebook(c *gin.Context) {
+	var req models.MoveNotebookRequest
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := h.GetAuthPayload(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	notebook, err := h.storage.Notebook().Move(id, payload.UserID, req.ParentID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, errorResponse(ErrNotebookNotFound))
+			return
+		}
+
+		if errors.Is(err, repo.ErrNotebookCycle) {
+			c.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, parseNotebookModel(notebook))
+}
+
+// @Security ApiKeyAuth
+// @Router /notebooks/{id} [delete]
+// @Summary Delete a notebook
+// @Description Delete a notebook with the notebooks inside of it. The notes are kept without a notebook
+// @Tags notebooks
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Success 200 {object} models.ResponseOK
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) DeleteNotebook(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := h.GetAuthPayload(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	err = h.storage.Notebook().Delete(id, payload.UserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, errorResponse(ErrNotebookNotFound))
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.ResponseOK{
+		Message: "Successfully deleted!",
+	})
+}
+
+// @Security ApiKeyAuth
+// @Router /notes/{id}/move [post]
+// @Summary Move a note
+// @Description Move a note into a notebook or out of its notebook
+// @Tags notes
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Param data body models.MoveNoteRequest true "Data"
+// @Success 200 {object} models.Note
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) MoveNote(c *gin.Context) {
+	var req models.MoveNoteRequest
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := h.GetAuthPayload(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if req.NotebookID != nil && !h.checkNotebook(c, *req.NotebookID, payload.UserID) {
+		return
+	}
+
+	err = h.storage.Note().Move(id, payload.UserID, req.NotebookID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, errorResponse(ErrNoteNotFound))
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	note, err := h.storage.Note().Get(id, payload.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	respondNote(c, http.StatusOK, note)
+}
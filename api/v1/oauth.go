@@ -0,0 +1,757 @@
+package v1
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/mirasildev/note_project/api/models"
+	"github.com/mirasildev/note_project/pkg/oidc"
+	"github.com/mirasildev/note_project/pkg/utils"
+	"github.com/mirasildev/note_project/storage"
+	"github.com/mirasildev/note_project/storage/repo"
+)
+
+const (
+	OAuthCodeKey     = "oauth_code_"
+	OAuthCodeUsedKey = "oauth_code_used_"
+
+	oauthCodeTTL = 5 * time.Minute
+)
+
+// Error codes of RFC 6749 returned by the token endpoints
+const (
+	oauthErrInvalidRequest       = "invalid_request"
+	oauthErrInvalidClient        = "invalid_client"
+	oauthErrInvalidGrant         = "invalid_grant"
+	oauthErrUnsupportedGrantType = "unsupported_grant_type"
+	oauthErrAccessDenied         = "access_denied"
+)
+
+var (
+	ErrOAuthClientNotFound = errors.New("oauth client not found")
+	ErrInvalidRedirectURI  = errors.New("redirect_uri is not registered for the client")
+	ErrInvalidScope        = errors.New("scope is not allowed for the client")
+	ErrInvalidClient       = errors.New("client authentication failed")
+	ErrInvalidAuthCode     = errors.New("authorization code is invalid, expired or has already been used")
+	ErrInvalidCodeVerifier = errors.New("code_verifier does not match the code_challenge")
+)
+
+// authorizationCodeData is what the user approved, kept until the client redeems the code
+type authorizationCodeData struct {
+	ClientID      string   `json:"client_id"`
+	UserID        int64    `json:"user_id"`
+	RedirectURI   string   `json:"redirect_uri"`
+	Scopes        []string `json:"scopes"`
+	CodeChallenge string   `json:"code_challenge"`
+}
+
+// @Security ApiKeyAuth
+// @Router /oauth/clients [post]
+// @Summary Register an OAuth client
+// @Description Register a third-party app. The secret of a confidential client is returned only once
+// @Tags oauth
+// @Accept json
+// @Produce json
+// @Param data body models.CreateOAuthClientRequest true "Data"
+// @Success 201 {object} models.CreateOAuthClientResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) CreateOAuthClient(c *gin.Context) {
+	var req models.CreateOAuthClientRequest
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := h.GetAuthPayload(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	client := &repo.OAuthClient{
+		ID:           uuid.NewString(),
+		UserID:       payload.UserID,
+		Name:         req.Name,
+		RedirectURIs: req.RedirectURIs,
+		Scopes:       req.Scopes,
+	}
+
+	var secret string
+	if req.Confidential {
+		secret, err = utils.GenerateRandomToken(32)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		secretHash := utils.HashToken(secret)
+		client.SecretHash = &secretHash
+	}
+
+	resp, err := h.storage.OAuthClient().Create(client)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.CreateOAuthClientResponse{
+		OAuthClient:  parseOAuthClientModel(resp),
+		ClientSecret: secret,
+	})
+}
+
+func parseOAuthClientModel(client *repo.OAuthClient) models.OAuthClient {
+	return models.OAuthClient{
+		ID:           client.ID,
+		Name:         client.Name,
+		RedirectURIs: client.RedirectURIs,
+		Scopes:       client.Scopes,
+		Confidential: client.SecretHash != nil,
+		CreatedAt:    client.CreatedAt,
+	}
+}
+
+// @Security ApiKeyAuth
+// @Router /oauth/clients [get]
+// @Summary Get OAuth clients
+// @Description Get the OAuth clients registered by the authenticated user
+// @Tags oauth
+// @Accept json
+// @Produce json
+// @Success 200 {object} models.GetAllOAuthClientsResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) GetOAuthClients(c *gin.Context) {
+	payload, err := h.GetAuthPayload(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	clients, err := h.storage.OAuthClient().GetAll(payload.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	response := models.GetAllOAuthClientsResponse{
+		Clients: make([]*models.OAuthClient, 0),
+	}
+	for _, client := range clients {
+		p := parseOAuthClientModel(client)
+		response.Clients = append(response.Clients, &p)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// @Security ApiKeyAuth
+// @Router /oauth/clients/{id} [delete]
+// @Summary Delete an OAuth client
+// @Description Delete an OAuth client. Every token issued to it stops working
+// @Tags oauth
+// @Accept json
+// @Produce json
+// @Param id path string true "ID"
+// @Success 200 {object} models.ResponseOK
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) DeleteOAuthClient(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := h.GetAuthPayload(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	err = h.storage.OAuthClient().Delete(id.String(), payload.UserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, errorResponse(ErrOAuthClientNotFound))
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	sessions, err := h.storage.Session().GetAllByClient(id.String())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	for _, s := range sessions {
+		err = h.revokeRefreshTokenFamily(s.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, models.ResponseOK{
+		Message: "Successfully deleted!",
+	})
+}
+
+// @Security ApiKeyAuth
+// @Router /oauth/authorize [get]
+// @Summary Start an OAuth authorization
+// @Description Validate an authorization request and describe it for the consent screen. PKCE with S256 is required
+// @Tags oauth
+// @Accept json
+// @Produce json
+// @Param filter query models.OAuthAuthorizeRequest true "Filter"
+// @Success 200 {object} models.OAuthConsentResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) OAuthAuthorize(c *gin.Context) {
+	var req models.OAuthAuthorizeRequest
+
+	err := c.ShouldBindQuery(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	client, scopes, err := h.validateAuthorizeRequest(&req)
+	if err != nil {
+		if errors.Is(err, ErrOAuthClientNotFound) || errors.Is(err, ErrInvalidRedirectURI) ||
+			errors.Is(err, ErrInvalidScope) {
+			c.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.OAuthConsentResponse{
+		ClientID:    client.ID,
+		ClientName:  client.Name,
+		Scopes:      scopes,
+		RedirectURI: req.RedirectURI,
+		State:       req.State,
+	})
+}
+
+// @Security ApiKeyAuth
+// @Router /oauth/authorize [post]
+// @Summary Answer an OAuth authorization
+// @Description Approve or deny the access the client asked for. The user agent should be sent to redirect_to
+// @Tags oauth
+// @Accept json
+// @Produce json
+// @Param data body models.OAuthConsentRequest true "Data"
+// @Success 200 {object} models.OAuthRedirectResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) OAuthConsent(c *gin.Context) {
+	var req models.OAuthConsentRequest
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	client, scopes, err := h.validateAuthorizeRequest(&req.OAuthAuthorizeRequest)
+	if err != nil {
+		if errors.Is(err, ErrOAuthClientNotFound) || errors.Is(err, ErrInvalidRedirectURI) ||
+			errors.Is(err, ErrInvalidScope) {
+			c.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	params := url.Values{}
+	if req.State != "" {
+		params.Set("state", req.State)
+	}
+
+	if !req.Approve {
+		params.Set("error", oauthErrAccessDenied)
+		c.JSON(http.StatusOK, models.OAuthRedirectResponse{
+			RedirectTo: redirectWithParams(req.RedirectURI, params),
+		})
+		return
+	}
+
+	payload, err := h.GetAuthPayload(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	code, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	data, err := json.Marshal(authorizationCodeData{
+		ClientID:      client.ID,
+		UserID:        payload.UserID,
+		RedirectURI:   req.RedirectURI,
+		Scopes:        scopes,
+		CodeChallenge: req.CodeChallenge,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = h.inMemory.Set(OAuthCodeKey+utils.HashToken(code), string(data), oauthCodeTTL)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	params.Set("code", code)
+	c.JSON(http.StatusOK, models.OAuthRedirectResponse{
+		RedirectTo: redirectWithParams(req.RedirectURI, params),
+	})
+}
+
+// validateAuthorizeRequest checks the request against the registered client
+// and returns the client with the requested scopes
+func (h *handlerV1) validateAuthorizeRequest(req *models.OAuthAuthorizeRequest) (*repo.OAuthClient, []string, error) {
+	client, err := h.storage.OAuthClient().Get(req.ClientID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, ErrOAuthClientNotFound
+		}
+		return nil, nil, err
+	}
+
+	if !containsString(client.RedirectURIs, req.RedirectURI) {
+		return nil, nil, ErrInvalidRedirectURI
+	}
+
+	scopes := strings.Fields(req.Scope)
+	if len(scopes) == 0 {
+		return nil, nil, ErrInvalidScope
+	}
+
+	for _, scope := range scopes {
+		if !containsString(client.Scopes, scope) {
+			return nil, nil, ErrInvalidScope
+		}
+	}
+
+	return client, scopes, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// redirectWithParams adds the params to the query the redirect uri already has
+func redirectWithParams(redirectURI string, params url.Values) string {
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		return redirectURI
+	}
+
+	query := u.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	u.RawQuery = query.Encode()
+
+	return u.String()
+}
+
+// @Router /oauth/token [post]
+// @Summary Get OAuth tokens
+// @Description Redeem an authorization code or a refresh token. Confidential clients authenticate with HTTP Basic or client_secret
+// @Tags oauth
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param grant_type formData string true "authorization_code or refresh_token"
+// @Param client_id formData string false "Client ID"
+// @Param client_secret formData string false "Client secret"
+// @Param code formData string false "Authorization code"
+// @Param redirect_uri formData string false "Redirect URI"
+// @Param code_verifier formData string false "PKCE code verifier"
+// @Param refresh_token formData string false "Refresh token"
+// @Success 200 {object} models.OAuthTokenResponse
+// @Failure 400 {object} models.OAuthErrorResponse
+// @Failure 401 {object} models.OAuthErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) OAuthToken(c *gin.Context) {
+	client, err := h.authenticateOAuthClient(c)
+	if err != nil {
+		if errors.Is(err, ErrInvalidClient) {
+			c.JSON(http.StatusUnauthorized, oauthErrorResponse(oauthErrInvalidClient, err))
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	var (
+		resp   *models.AuthResponse
+		scopes []string
+	)
+
+	switch c.PostForm("grant_type") {
+	case "authorization_code":
+		resp, scopes, err = h.redeemAuthorizationCode(c, client)
+	case "refresh_token":
+		var family *refreshTokenData
+		family, err = h.getRefreshTokenFamily(c.PostForm("refresh_token"))
+		if err == nil {
+			scopes = family.Scopes
+			resp, err = h.rotateRefreshToken(c.PostForm("refresh_token"), client.ID)
+		}
+	default:
+		c.JSON(http.StatusBadRequest, oauthErrorResponse(oauthErrUnsupportedGrantType, nil))
+		return
+	}
+
+	if err != nil {
+		if errors.Is(err, ErrInvalidAuthCode) || errors.Is(err, ErrInvalidCodeVerifier) ||
+			errors.Is(err, ErrInvalidRedirectURI) || errors.Is(err, ErrInvalidRefreshToken) ||
+			errors.Is(err, ErrRefreshTokenReused) || errors.Is(err, ErrTokenRevoked) ||
+			errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusBadRequest, oauthErrorResponse(oauthErrInvalidGrant, err))
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, models.OAuthTokenResponse{
+		AccessToken:  resp.AccessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(time.Until(resp.ExpiresAt).Seconds()),
+		RefreshToken: resp.RefreshToken,
+		Scope:        strings.Join(scopes, " "),
+	})
+}
+
+// redeemAuthorizationCode exchanges a code issued to the client for tokens of a new session
+func (h *handlerV1) redeemAuthorizationCode(c *gin.Context, client *repo.OAuthClient) (*models.AuthResponse, []string, error) {
+	codeHash := utils.HashToken(c.PostForm("code"))
+
+	firstUse, err := h.inMemory.SetNX(OAuthCodeUsedKey+codeHash, "1", oauthCodeTTL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !firstUse {
+		return nil, nil, ErrInvalidAuthCode
+	}
+
+	data, err := h.inMemory.Get(OAuthCodeKey + codeHash)
+	if err != nil {
+		if errors.Is(err, storage.ErrKeyNotFound) {
+			return nil, nil, ErrInvalidAuthCode
+		}
+		return nil, nil, err
+	}
+
+	err = h.inMemory.Delete(OAuthCodeKey + codeHash)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var code authorizationCodeData
+	err = json.Unmarshal([]byte(data), &code)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if code.ClientID != client.ID {
+		return nil, nil, ErrInvalidAuthCode
+	}
+
+	if code.RedirectURI != c.PostForm("redirect_uri") {
+		return nil, nil, ErrInvalidRedirectURI
+	}
+
+	challenge := oidc.CodeChallenge(c.PostForm("code_verifier"))
+	if subtle.ConstantTimeCompare([]byte(challenge), []byte(code.CodeChallenge)) != 1 {
+		return nil, nil, ErrInvalidCodeVerifier
+	}
+
+	user, err := h.storage.User().Get(code.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resp, err := h.startSession(c, user, client.ID, code.Scopes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return resp, code.Scopes, nil
+}
+
+// authenticateOAuthClient identifies the client of a token endpoint request.
+// Confidential clients must present their secret, public clients only their id.
+func (h *handlerV1) authenticateOAuthClient(c *gin.Context) (*repo.OAuthClient, error) {
+	clientID, secret, ok := c.Request.BasicAuth()
+	if !ok {
+		clientID = c.PostForm("client_id")
+		secret = c.PostForm("client_secret")
+	}
+
+	if clientID == "" {
+		return nil, ErrInvalidClient
+	}
+
+	client, err := h.storage.OAuthClient().Get(clientID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrInvalidClient
+		}
+		return nil, err
+	}
+
+	if client.SecretHash == nil {
+		if secret != "" {
+			return nil, ErrInvalidClient
+		}
+		return client, nil
+	}
+
+	if subtle.ConstantTimeCompare([]byte(utils.HashToken(secret)), []byte(*client.SecretHash)) != 1 {
+		return nil, ErrInvalidClient
+	}
+
+	return client, nil
+}
+
+func oauthErrorResponse(code string, err error) models.OAuthErrorResponse {
+	resp := models.OAuthErrorResponse{
+		Error: code,
+	}
+	if err != nil {
+		resp.ErrorDescription = err.Error()
+	}
+	return resp
+}
+
+// @Router /oauth/introspect [post]
+// @Summary Introspect an OAuth token
+// @Description Tell whether an access or refresh token issued to the client is active (RFC 7662)
+// @Tags oauth
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param token formData string true "Token"
+// @Param client_id formData string false "Client ID"
+// @Param client_secret formData string false "Client secret"
+// @Success 200 {object} models.OAuthIntrospectionResponse
+// @Failure 401 {object} models.OAuthErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) OAuthIntrospect(c *gin.Context) {
+	client, err := h.authenticateOAuthClient(c)
+	if err != nil {
+		if errors.Is(err, ErrInvalidClient) {
+			c.JSON(http.StatusUnauthorized, oauthErrorResponse(oauthErrInvalidClient, err))
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	token := c.PostForm("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, oauthErrorResponse(oauthErrInvalidRequest, nil))
+		return
+	}
+
+	family, err := h.activeRefreshTokenFamily(token)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if family != nil {
+		if family.ClientID != client.ID {
+			c.JSON(http.StatusOK, models.OAuthIntrospectionResponse{})
+			return
+		}
+
+		c.JSON(http.StatusOK, models.OAuthIntrospectionResponse{
+			Active:    true,
+			Scope:     strings.Join(family.Scopes, " "),
+			ClientID:  family.ClientID,
+			Sub:       strconv.FormatInt(family.UserID, 10),
+			TokenType: "refresh_token",
+			Exp:       family.ExpiresAt.Unix(),
+			Iat:       family.IssuedAt.Unix(),
+		})
+		return
+	}
+
+	payload, err := h.clientAccessToken(token, client.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if payload == nil {
+		c.JSON(http.StatusOK, models.OAuthIntrospectionResponse{})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.OAuthIntrospectionResponse{
+		Active:    true,
+		Scope:     strings.Join(payload.Scopes, " "),
+		ClientID:  payload.ClientID,
+		Sub:       strconv.FormatInt(payload.UserID, 10),
+		TokenType: "access_token",
+		Exp:       payload.ExpiredAt.Unix(),
+		Iat:       payload.IssuedAt.Unix(),
+	})
+}
+
+// @Router /oauth/revoke [post]
+// @Summary Revoke an OAuth token
+// @Description Revoke an access or refresh token issued to the client (RFC 7009). Revoking a refresh token ends its session
+// @Tags oauth
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param token formData string true "Token"
+// @Param client_id formData string false "Client ID"
+// @Param client_secret formData string false "Client secret"
+// @Success 200 {object} models.ResponseOK
+// @Failure 401 {object} models.OAuthErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) OAuthRevoke(c *gin.Context) {
+	client, err := h.authenticateOAuthClient(c)
+	if err != nil {
+		if errors.Is(err, ErrInvalidClient) {
+			c.JSON(http.StatusUnauthorized, oauthErrorResponse(oauthErrInvalidClient, err))
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	token := c.PostForm("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, oauthErrorResponse(oauthErrInvalidRequest, nil))
+		return
+	}
+
+	family, err := h.activeRefreshTokenFamily(token)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	var userID int64
+	if family != nil {
+		if family.ClientID == client.ID {
+			userID = family.UserID
+			err = h.revokeRefreshTokenFamily(family.FamilyID)
+		}
+	} else {
+		var payload *utils.Payload
+		payload, err = h.clientAccessToken(token, client.ID)
+		if err == nil && payload != nil {
+			userID = payload.UserID
+			err = h.revokeAccessToken(payload)
+		}
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if userID != 0 {
+		h.audit(c, repo.AuditEventTokenRevoked, userID, "")
+	}
+
+	// Unknown and already revoked tokens are not an error, see RFC 7009
+	c.JSON(http.StatusOK, models.ResponseOK{
+		Message: "Successfully revoked!",
+	})
+}
+
+// activeRefreshTokenFamily returns the family of a refresh token
+// which was not rotated yet, or nil if the token is not one
+func (h *handlerV1) activeRefreshTokenFamily(token string) (*refreshTokenData, error) {
+	family, err := h.getRefreshTokenFamily(token)
+	if errors.Is(err, ErrInvalidRefreshToken) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = h.inMemory.Get(RefreshTokenUsedKey + utils.HashToken(token))
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.Is(err, storage.ErrKeyNotFound) {
+		return nil, err
+	}
+
+	_, err = h.inMemory.Get(RefreshTokenFamilyKey + family.FamilyID)
+	if errors.Is(err, storage.ErrKeyNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return family, nil
+}
+
+// clientAccessToken returns the payload of a valid access token
+// issued to the client, or nil if the token is not one
+func (h *handlerV1) clientAccessToken(token, clientID string) (*utils.Payload, error) {
+	if strings.HasPrefix(token, personalAccessTokenPrefix) {
+		return nil, nil
+	}
+
+	payload, err := h.verifyAccessToken(token)
+	if err != nil {
+		if errors.Is(err, utils.ErrInvalidToken) || errors.Is(err, utils.ErrExpiredToken) ||
+			errors.Is(err, ErrTokenRevoked) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if payload.ClientID != clientID {
+		return nil, nil
+	}
+
+	return payload, nil
+}
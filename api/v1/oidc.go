@@ -0,0 +1,265 @@
+package v1
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mirasildev/note_project/pkg/oidc"
+	"github.com/mirasildev/note_project/pkg/utils"
+	"github.com/mirasildev/note_project/storage"
+	"github.com/mirasildev/note_project/storage/repo"
+)
+
+const (
+	OidcStateKey     = "oidc_state_"
+	OidcStateUsedKey = "oidc_state_used_"
+)
+
+// oidcStateTTL is how long the user has to sign in at the identity provider
+const oidcStateTTL = 10 * time.Minute
+
+// maxNameLength is the length of the first_name and last_name columns
+const maxNameLength = 30
+
+var (
+	ErrInvalidOidcState      = errors.New("sign in request is invalid or expired, start again")
+	ErrOidcEmailNotVerified  = errors.New("email is not verified by the identity provider")
+	ErrOidcSignInFailed      = errors.New("failed to sign in with the identity provider")
+	ErrOidcProviderRejection = errors.New("identity provider rejected the sign in")
+)
+
+type oidcState struct {
+	Provider     string `json:"provider"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"`
+}
+
+// @Router /auth/oidc/{provider}/login [get]
+// @Summary Sign in with an identity provider
+// @Description Redirect to the identity provider to sign in
+// @Tags auth
+// @Param provider path string true "Provider"
+// @Success 302
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) OidcLogin(c *gin.Context) {
+	name := c.Param("provider")
+
+	provider, err := h.oidc.Get(c.Request.Context(), name)
+	if err != nil {
+		if errors.Is(err, oidc.ErrUnknownProvider) {
+			c.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	var values [3]string
+	for i := range values {
+		values[i], err = utils.GenerateRandomToken(32)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+	}
+
+	state, nonce, codeVerifier := values[0], values[1], values[2]
+
+	data, err := json.Marshal(oidcState{
+		Provider:     name,
+		Nonce:        nonce,
+		CodeVerifier: codeVerifier,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = h.inMemory.Set(OidcStateKey+state, string(data), oidcStateTTL)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	c.Redirect(http.StatusFound, provider.AuthCodeURL(state, nonce, codeVerifier))
+}
+
+// @Router /auth/oidc/{provider}/callback [get]
+// @Summary Identity provider callback
+// @Description Finish signing in with the identity provider. The response is the same as of login
+// @Tags auth
+// @Produce json
+// @Param provider path string true "Provider"
+// @Param code query string true "Code"
+// @Param state query string true "State"
+// @Success 201 {object} models.AuthResponse
+// @Success 200 {object} models.TwoFactorChallengeResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) OidcCallback(c *gin.Context) {
+	name := c.Param("provider")
+
+	if c.Query("error") != "" {
+		c.JSON(http.StatusUnauthorized, errorResponse(ErrOidcProviderRejection))
+		return
+	}
+
+	state, err := h.takeOidcState(c.Query("state"))
+	if err != nil {
+		if errors.Is(err, storage.ErrKeyNotFound) {
+			c.JSON(http.StatusBadRequest, errorResponse(ErrInvalidOidcState))
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if state.Provider != name {
+		c.JSON(http.StatusBadRequest, errorResponse(ErrInvalidOidcState))
+		return
+	}
+
+	provider, err := h.oidc.Get(c.Request.Context(), name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	identity, err := provider.Exchange(c.Request.Context(), c.Query("code"), state.CodeVerifier, state.Nonce)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, errorResponse(ErrOidcSignInFailed))
+		return
+	}
+
+	user, err := h.getOidcUser(name, identity)
+	if err != nil {
+		if errors.Is(err, ErrOidcEmailNotVerified) {
+			c.JSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	h.completeLogin(c, user)
+}
+
+// takeOidcState returns the state of the sign in request and removes it,
+// so a callback can't be replayed. Only the first of concurrent callbacks
+// with the same state gets it.
+func (h *handlerV1) takeOidcState(state string) (*oidcState, error) {
+	if state == "" {
+		return nil, storage.ErrKeyNotFound
+	}
+
+	data, err := h.inMemory.Get(OidcStateKey + state)
+	if err != nil {
+		return nil, err
+	}
+
+	firstUse, err := h.inMemory.SetNX(OidcStateUsedKey+state, "1", oidcStateTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	if !firstUse {
+		return nil, storage.ErrKeyNotFound
+	}
+
+	err = h.inMemory.Delete(OidcStateKey + state)
+	if err != nil {
+		return nil, err
+	}
+
+	var result oidcState
+	err = json.Unmarshal([]byte(data), &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// getOidcUser returns the user linked to the identity. An identity signing in
+// for the first time is linked to the user with the same verified email,
+// a new user is created if there is none.
+func (h *handlerV1) getOidcUser(provider string, identity *oidc.Identity) (*repo.User, error) {
+	linked, err := h.storage.UserIdentity().Get(provider, identity.Subject)
+	if err == nil {
+		return h.storage.User().Get(linked.UserID)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	if identity.Email == "" || !identity.EmailVerified {
+		return nil, ErrOidcEmailNotVerified
+	}
+
+	user, err := h.storage.User().GetByEmail(identity.Email)
+	if errors.Is(err, sql.ErrNoRows) {
+		user, err = h.createOidcUser(identity)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = h.storage.UserIdentity().Create(&repo.UserIdentity{
+		UserID:   user.ID,
+		Provider: provider,
+		Subject:  identity.Subject,
+		Email:    identity.Email,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// createOidcUser creates a user without a known password,
+// one can be set later by resetting it
+func (h *handlerV1) createOidcUser(identity *oidc.Identity) (*repo.User, error) {
+	password, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	hashedPassword, err := utils.HashPassword(h.cfg, password)
+	if err != nil {
+		return nil, err
+	}
+
+	firstName := identity.GivenName
+	if firstName == "" {
+		firstName, _, _ = strings.Cut(identity.Email, "@")
+	}
+
+	return h.storage.User().Create(&repo.User{
+		FirstName: truncate(firstName, maxNameLength),
+		LastName:  truncate(identity.FamilyName, maxNameLength),
+		Email:     identity.Email,
+		Password:  hashedPassword,
+		Role:      repo.UserRoleUser,
+	})
+}
+
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) > n {
+		return string(runes[:n])
+	}
+
+	return s
+}
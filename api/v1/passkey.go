@@ -0,0 +1,440 @@
+package v1
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mirasildev/note_project/api/models"
+	"github.com/mirasildev/note_project/pkg/webauthn"
+	"github.com/mirasildev/note_project/storage"
+	"github.com/mirasildev/note_project/storage/repo"
+)
+
+const (
+	PasskeyRegistrationKey  = "passkey_registration_"
+	PasskeyLoginKey         = "passkey_login_"
+	PasskeyChallengeUsedKey = "passkey_challenge_used_"
+)
+
+var (
+	ErrPasskeyCeremonyExpired = errors.New("passkey request has expired, start again")
+	ErrPasskeyExists          = errors.New("passkey is already registered")
+	ErrPasskeyNotFound        = errors.New("passkey not found")
+	ErrPasskeyLoginFailed     = errors.New("failed to sign in with the passkey")
+)
+
+// usePasskeyChallenge marks the challenge as answered. It responds with an
+// error and returns false when a concurrent request has already answered it
+func (h *handlerV1) usePasskeyChallenge(c *gin.Context, challenge string) bool {
+	firstUse, err := h.inMemory.SetNX(PasskeyChallengeUsedKey+challenge, "1", webauthn.Timeout)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return false
+	}
+
+	if !firstUse {
+		c.JSON(http.StatusBadRequest, errorResponse(ErrPasskeyCeremonyExpired))
+		return false
+	}
+
+	return true
+}
+
+// @Security ApiKeyAuth
+// @Router /me/passkeys/register/begin [post]
+// @Summary Begin passkey registration
+// @Description Get the options for navigator.credentials.create()
+// @Tags passkeys
+// @Accept json
+// @Produce json
+// @Success 200 {object} webauthn.CreationOptions
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) BeginPasskeyRegistration(c *gin.Context) {
+	payload, err := h.GetAuthPayload(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	user, err := h.storage.User().Get(payload.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	passkeys, err := h.storage.Passkey().GetAll(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	exclude := make([][]byte, 0, len(passkeys))
+	for _, p := range passkeys {
+		id, err := base64.RawURLEncoding.DecodeString(p.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		exclude = append(exclude, id)
+	}
+
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = h.inMemory.Set(PasskeyRegistrationKey+strconv.FormatInt(user.ID, 10), challenge, webauthn.Timeout)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, h.webauthn.BeginRegistration(challenge, &webauthn.User{
+		ID:          userHandle(user.ID),
+		Name:        user.Email,
+		DisplayName: user.FirstName + " " + user.LastName,
+	}, exclude))
+}
+
+// @Security ApiKeyAuth
+// @Router /me/passkeys/register/finish [post]
+// @Summary Finish passkey registration
+// @Description Save the credential returned by navigator.credentials.create(). The client is signed in with the new passkey
+// @Tags passkeys
+// @Accept json
+// @Produce json
+// @Param data body models.FinishPasskeyRegistrationRequest true "Data"
+// @Success 201 {object} models.AuthResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) FinishPasskeyRegistration(c *gin.Context) {
+	var req models.FinishPasskeyRegistrationRequest
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := h.GetAuthPayload(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	challengeKey := PasskeyRegistrationKey + strconv.FormatInt(payload.UserID, 10)
+
+	challenge, err := h.inMemory.Get(challengeKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrKeyNotFound) {
+			c.JSON(http.StatusBadRequest, errorResponse(ErrPasskeyCeremonyExpired))
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if !h.usePasskeyChallenge(c, challenge) {
+		return
+	}
+
+	err = h.inMemory.Delete(challengeKey)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	credential, err := h.webauthn.FinishRegistration(challenge, &req.Credential)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	id := base64.RawURLEncoding.EncodeToString(credential.ID)
+
+	_, err = h.storage.Passkey().Get(id)
+	if !errors.Is(err, sql.ErrNoRows) {
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		c.JSON(http.StatusConflict, errorResponse(ErrPasskeyExists))
+		return
+	}
+
+	_, err = h.storage.Passkey().Create(&repo.Passkey{
+		ID:        id,
+		UserID:    payload.UserID,
+		Name:      req.Name,
+		PublicKey: credential.PublicKey,
+		SignCount: credential.SignCount,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	user, err := h.storage.User().Get(payload.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp, err := h.newAuthResponse(c, user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusCreated, resp)
+}
+
+// @Security ApiKeyAuth
+// @Router /me/passkeys [get]
+// @Summary Get passkeys
+// @Description Get all passkeys of the authenticated user
+// @Tags passkeys
+// @Accept json
+// @Produce json
+// @Success 200 {object} models.GetAllPasskeysResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) GetPasskeys(c *gin.Context) {
+	payload, err := h.GetAuthPayload(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	passkeys, err := h.storage.Passkey().GetAll(payload.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	response := models.GetAllPasskeysResponse{
+		Passkeys: make([]*models.Passkey, 0),
+	}
+	for _, p := range passkeys {
+		response.Passkeys = append(response.Passkeys, &models.Passkey{
+			ID:         p.ID,
+			Name:       p.Name,
+			LastUsedAt: p.LastUsedAt,
+			CreatedAt:  p.CreatedAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// @Security ApiKeyAuth
+// @Router /me/passkeys/{id} [delete]
+// @Summary Delete a passkey
+// @Description Delete a passkey, it can't be used to sign in anymore
+// @Tags passkeys
+// @Accept json
+// @Produce json
+// @Param id path string true "ID"
+// @Success 200 {object} models.ResponseOK
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) DeletePasskey(c *gin.Context) {
+	payload, err := h.GetAuthPayload(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	err = h.storage.Passkey().Delete(c.Param("id"), payload.UserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, errorResponse(ErrPasskeyNotFound))
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.ResponseOK{
+		Message: "Successfully deleted!",
+	})
+}
+
+// @Router /auth/passkey/login/begin [post]
+// @Summary Begin passkey login
+// @Description Get the options for navigator.credentials.get()
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param data body models.BeginPasskeyLoginRequest true "Data"
+// @Success 200 {object} webauthn.RequestOptions
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) BeginPasskeyLogin(c *gin.Context) {
+	var req models.BeginPasskeyLoginRequest
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	// Unknown emails get the same response as users without passkeys,
+	// so the endpoint can't be used to find out who is registered
+	allow := make([][]byte, 0)
+	if req.Email != "" {
+		user, err := h.storage.User().GetByEmail(req.Email)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		if err == nil {
+			passkeys, err := h.storage.Passkey().GetAll(user.ID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, errorResponse(err))
+				return
+			}
+
+			for _, p := range passkeys {
+				id, err := base64.RawURLEncoding.DecodeString(p.ID)
+				if err != nil {
+					c.JSON(http.StatusInternalServerError, errorResponse(err))
+					return
+				}
+				allow = append(allow, id)
+			}
+		}
+	}
+
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = h.inMemory.Set(PasskeyLoginKey+challenge, "1", webauthn.Timeout)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, h.webauthn.BeginLogin(challenge, allow))
+}
+
+// @Router /auth/passkey/login/finish [post]
+// @Summary Finish passkey login
+// @Description Sign in with the assertion returned by navigator.credentials.get()
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param data body webauthn.AssertionResponse true "Data"
+// @Success 201 {object} models.AuthResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) FinishPasskeyLogin(c *gin.Context) {
+	var req webauthn.AssertionResponse
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	challenge, err := webauthn.ChallengeOf(req.Response.ClientDataJSON)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	// Every challenge may be answered once
+	if !h.usePasskeyChallenge(c, challenge) {
+		return
+	}
+
+	_, err = h.inMemory.Get(PasskeyLoginKey + challenge)
+	if err != nil {
+		if errors.Is(err, storage.ErrKeyNotFound) {
+			c.JSON(http.StatusBadRequest, errorResponse(ErrPasskeyCeremonyExpired))
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = h.inMemory.Delete(PasskeyLoginKey + challenge)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	passkey, err := h.storage.Passkey().Get(strings.TrimRight(req.ID, "="))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusUnauthorized, errorResponse(ErrPasskeyLoginFailed))
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if req.Response.UserHandle != "" &&
+		strings.TrimRight(req.Response.UserHandle, "=") != base64.RawURLEncoding.EncodeToString(userHandle(passkey.UserID)) {
+		c.JSON(http.StatusUnauthorized, errorResponse(ErrPasskeyLoginFailed))
+		return
+	}
+
+	signCount, err := h.webauthn.FinishLogin(challenge, &webauthn.Credential{
+		PublicKey: passkey.PublicKey,
+		SignCount: passkey.SignCount,
+	}, &req)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, errorResponse(ErrPasskeyLoginFailed))
+		return
+	}
+
+	err = h.storage.Passkey().UpdateSignCount(passkey.ID, signCount, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	user, err := h.storage.User().Get(passkey.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if user.DeletionScheduledAt != nil {
+		c.JSON(http.StatusForbidden, errorResponse(ErrAccountDeletionScheduled))
+		return
+	}
+
+	resp, err := h.newAuthResponse(c, user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	h.audit(c, repo.AuditEventLoginSucceeded, user.ID, user.Email)
+
+	c.JSON(http.StatusCreated, resp)
+}
+
+// userHandle is the WebAuthn user id of the user
+func userHandle(userID int64) []byte {
+	return []byte(strconv.FormatInt(userID, 10))
+}
@@ -0,0 +1,225 @@
+package v1
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/mirasildev/note_project/api/models"
+	"github.com/mirasildev/note_project/pkg/utils"
+	"github.com/mirasildev/note_project/storage/repo"
+)
+
+const (
+	PersonalAccessTokenUsedKey = "personal_access_token_used_"
+
+	personalAccessTokenPrefix = "pat_"
+	// lastUsedInterval limits how often last_used_at is written for a token
+	lastUsedInterval = time.Minute
+)
+
+var (
+	ErrTokenExpiresInPast = errors.New("expires_at must be in the future")
+	ErrTokenNotFound      = errors.New("token not found")
+)
+
+// @Security ApiKeyAuth
+// @Router /me/tokens [post]
+// @Summary Create a personal access token
+// @Description Create a scoped token for scripts and integrations. The token is returned only once
+// @Tags personal-access-tokens
+// @Accept json
+// @Produce json
+// @Param data body models.CreatePersonalAccessTokenRequest true "Data"
+// @Success 201 {object} models.CreatePersonalAccessTokenResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) CreatePersonalAccessToken(c *gin.Context) {
+	var req models.CreatePersonalAccessTokenRequest
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if req.ExpiresAt != nil && req.ExpiresAt.Before(time.Now()) {
+		c.JSON(http.StatusBadRequest, errorResponse(ErrTokenExpiresInPast))
+		return
+	}
+
+	payload, err := h.GetAuthPayload(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	secret, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	token := personalAccessTokenPrefix + secret
+
+	resp, err := h.storage.PersonalAccessToken().Create(&repo.PersonalAccessToken{
+		ID:        uuid.NewString(),
+		UserID:    payload.UserID,
+		Name:      req.Name,
+		TokenHash: utils.HashToken(token),
+		Scopes:    req.Scopes,
+		ExpiresAt: req.ExpiresAt,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.CreatePersonalAccessTokenResponse{
+		PersonalAccessToken: parsePersonalAccessTokenModel(resp),
+		Token:               token,
+	})
+}
+
+func parsePersonalAccessTokenModel(t *repo.PersonalAccessToken) models.PersonalAccessToken {
+	return models.PersonalAccessToken{
+		ID:         t.ID,
+		Name:       t.Name,
+		Scopes:     t.Scopes,
+		ExpiresAt:  t.ExpiresAt,
+		LastUsedAt: t.LastUsedAt,
+		CreatedAt:  t.CreatedAt,
+	}
+}
+
+// @Security ApiKeyAuth
+// @Router /me/tokens [get]
+// @Summary Get personal access tokens
+// @Description Get all personal access tokens of the authenticated user
+// @Tags personal-access-tokens
+// @Accept json
+// @Produce json
+// @Success 200 {object} models.GetAllPersonalAccessTokensResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) GetPersonalAccessTokens(c *gin.Context) {
+	payload, err := h.GetAuthPayload(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	tokens, err := h.storage.PersonalAccessToken().GetAll(payload.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	response := models.GetAllPersonalAccessTokensResponse{
+		Tokens: make([]*models.PersonalAccessToken, 0),
+	}
+	for _, t := range tokens {
+		p := parsePersonalAccessTokenModel(t)
+		response.Tokens = append(response.Tokens, &p)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// @Security ApiKeyAuth
+// @Router /me/tokens/{id} [delete]
+// @Summary Revoke a personal access token
+// @Description Revoke a personal access token
+// @Tags personal-access-tokens
+// @Accept json
+// @Produce json
+// @Param id path string true "ID"
+// @Success 200 {object} models.ResponseOK
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) DeletePersonalAccessToken(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := h.GetAuthPayload(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	err = h.storage.PersonalAccessToken().Delete(id.String(), payload.UserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, errorResponse(ErrTokenNotFound))
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	h.audit(c, repo.AuditEventTokenRevoked, payload.UserID, payload.Email)
+
+	c.JSON(http.StatusOK, models.ResponseOK{
+		Message: "Successfully revoked!",
+	})
+}
+
+// verifyPersonalAccessToken builds the same payload a JWT carries
+// from a stored personal access token
+func (h *handlerV1) verifyPersonalAccessToken(token string) (*utils.Payload, error) {
+	pat, err := h.storage.PersonalAccessToken().GetByHash(utils.HashToken(token))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, utils.ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	if pat.ExpiresAt != nil && time.Now().After(*pat.ExpiresAt) {
+		return nil, utils.ErrExpiredToken
+	}
+
+	id, err := uuid.Parse(pat.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := h.storage.User().Get(pat.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.DeletionScheduledAt != nil {
+		return nil, ErrTokenRevoked
+	}
+
+	firstUse, err := h.inMemory.SetNX(PersonalAccessTokenUsedKey+pat.ID, "1", lastUsedInterval)
+	if err != nil {
+		return nil, err
+	}
+
+	if firstUse {
+		err = h.storage.PersonalAccessToken().UpdateLastUsed(pat.ID, time.Now())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	payload := &utils.Payload{
+		ID:       id,
+		UserID:   user.ID,
+		Email:    user.Email,
+		Role:     user.Role,
+		Scopes:   pat.Scopes,
+		IssuedAt: pat.CreatedAt,
+	}
+	if pat.ExpiresAt != nil {
+		payload.ExpiredAt = *pat.ExpiresAt
+	}
+
+	return payload, nil
+}
@@ -0,0 +1,208 @@
+package v1
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mirasildev/note_project/api/models"
+	emailPkg "github.com/mirasildev/note_project/pkg/email"
+	"github.com/mirasildev/note_project/storage"
+	"github.com/mirasildev/note_project/storage/repo"
+)
+
+const (
+	RegisterCodeAttemptsKey = "register_code_attempts_"
+	ResendCodeCooldownKey   = "resend_code_cooldown_"
+)
+
+const (
+	// registrationTTL is how long a registration stays pending without being verified
+	registrationTTL     = time.Hour
+	verificationCodeTTL = 5 * time.Minute
+	resendCodeCooldown  = time.Minute
+	maxCodeAttempts     = 5
+)
+
+// States of a pending registration reported to the client
+const (
+	RegistrationStatusPending         = "pending"
+	RegistrationStatusVerified        = "verified"
+	RegistrationStatusNotFound        = "not_found"
+	RegistrationStatusCodeExpired     = "code_expired"
+	RegistrationStatusCodeIncorrect   = "code_incorrect"
+	RegistrationStatusTooManyAttempts = "too_many_attempts"
+)
+
+var (
+	ErrRegistrationPending  = errors.New("registration is already pending, verify it or resend the code")
+	ErrRegistrationNotFound = errors.New("registration not found or expired, register again")
+	ErrTooManyCodeAttempts  = errors.New("verification code was checked too many times, resend the code")
+	ErrResendTooSoon        = errors.New("verification code was sent recently, try again later")
+)
+
+type registrationCheck struct {
+	Status       string
+	AttemptsLeft int
+}
+
+// @Router /auth/resend-code [post]
+// @Summary Resend verification code
+// @Description Send a new verification code for a pending registration
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param data body models.ResendCodeRequest true "Data"
+// @Success 200 {object} models.ResponseOK
+// @Failure 404 {object} models.VerificationErrorResponse
+// @Failure 429 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) ResendCode(c *gin.Context) {
+	var req models.ResendCodeRequest
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	userData, err := h.inMemory.Get(PendingUserKey + req.Email)
+	if err != nil {
+		if errors.Is(err, storage.ErrKeyNotFound) {
+			c.JSON(http.StatusNotFound, models.VerificationErrorResponse{
+				Error:  ErrRegistrationNotFound.Error(),
+				Status: RegistrationStatusNotFound,
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// taking the cooldown and checking it is one step,
+	// so only one of concurrent resends sends a code
+	firstResend, err := h.inMemory.SetNX(ResendCodeCooldownKey+req.Email, "1", resendCodeCooldown)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if !firstResend {
+		ttl, err := h.inMemory.TTL(ResendCodeCooldownKey + req.Email)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(ttl.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, errorResponse(ErrResendTooSoon))
+		return
+	}
+
+	// Keep the registration around for as long as the new code may be used
+	err = h.inMemory.Set(PendingUserKey+req.Email, userData, registrationTTL)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = h.startVerification(req.Email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.ResponseOK{
+		Message: "Verification code has been sent!",
+	})
+}
+
+// startVerification resets the attempts and sends a new registration code.
+// The caller starts the resend cooldown.
+func (h *handlerV1) startVerification(email string) error {
+	err := h.inMemory.Delete(RegisterCodeAttemptsKey + email)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		err := h.sendVerificationCode(RegisterCode, email, emailPkg.VerificationEmail)
+		if err != nil {
+			fmt.Printf("Failed to send verification code: %v", err)
+		}
+	}()
+
+	return nil
+}
+
+// checkRegistrationCode compares the code with the one sent for the pending
+// registration. The user is returned only when the status is verified.
+func (h *handlerV1) checkRegistrationCode(email, code string) (*repo.User, *registrationCheck, error) {
+	userData, err := h.inMemory.Get(PendingUserKey + email)
+	if errors.Is(err, storage.ErrKeyNotFound) {
+		return nil, &registrationCheck{Status: RegistrationStatusNotFound}, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sentCode, err := h.inMemory.Get(RegisterCode + email)
+	if errors.Is(err, storage.ErrKeyNotFound) {
+		return nil, &registrationCheck{Status: RegistrationStatusCodeExpired}, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	attempts, err := h.inMemory.Incr(RegisterCodeAttemptsKey+email, verificationCodeTTL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if attempts > maxCodeAttempts {
+		return nil, &registrationCheck{Status: RegistrationStatusTooManyAttempts}, nil
+	}
+
+	if code != sentCode {
+		if attempts == maxCodeAttempts {
+			return nil, &registrationCheck{Status: RegistrationStatusTooManyAttempts}, nil
+		}
+
+		return nil, &registrationCheck{
+			Status:       RegistrationStatusCodeIncorrect,
+			AttemptsLeft: maxCodeAttempts - int(attempts),
+		}, nil
+	}
+
+	var user repo.User
+	err = json.Unmarshal([]byte(userData), &user)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &user, &registrationCheck{Status: RegistrationStatusVerified}, nil
+}
+
+// finishVerification removes everything stored for a verified registration
+func (h *handlerV1) finishVerification(email string) error {
+	for _, key := range []string{
+		PendingUserKey + email,
+		RegisterCode + email,
+		RegisterCodeAttemptsKey + email,
+		ResendCodeCooldownKey + email,
+	} {
+		err := h.inMemory.Delete(key)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,168 @@
+package v1
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mirasildev/note_project/api/models"
+	"github.com/mirasildev/note_project/storage/repo"
+)
+
+const testCode = "123456"
+
+// createdUsers creates every user it is given
+type createdUsers struct {
+	fakeUsers
+}
+
+func (u *createdUsers) Create(user *repo.User) (*repo.User, error) {
+	created := *user
+	created.ID = 1
+	return &created, nil
+}
+
+func newRegistrationRouter() (*gin.Engine, *fakeInMemory) {
+	h := newTestHandler(&fakeStorage{
+		users:     &createdUsers{},
+		sessions:  &fakeSessions{revoked: make(map[string]bool)},
+		auditLogs: &fakeAuditLogs{},
+	})
+	h.cfg.AuthSecretKey = "secret"
+	h.cfg.AccessTokenDuration = time.Minute
+	h.cfg.RefreshTokenDuration = time.Hour
+
+	router := gin.New()
+	router.POST("/auth/verify", h.Verify)
+	router.POST("/auth/resend-code", h.ResendCode)
+
+	return router, h.inMemory.(*fakeInMemory)
+}
+
+// pendingRegistration stores a registration like Register does, with the code if it is set
+func pendingRegistration(t *testing.T, mem *fakeInMemory, code string) {
+	data, err := json.Marshal(repo.User{Email: testEmail, FirstName: "John"})
+	require.NoError(t, err)
+
+	require.NoError(t, mem.Set(PendingUserKey+testEmail, string(data), registrationTTL))
+	if code != "" {
+		require.NoError(t, mem.Set(RegisterCode+testEmail, code, verificationCodeTTL))
+	}
+}
+
+func postJSON(router *gin.Engine, path string, body interface{}) *httptest.ResponseRecorder {
+	data, _ := json.Marshal(body)
+
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(string(data)))
+	req.Header.Set("Content-Type", "application/json")
+
+	return performRequest(router, req)
+}
+
+func verify(t *testing.T, router *gin.Engine, code string, status int) models.VerificationErrorResponse {
+	resp := postJSON(router, "/auth/verify", models.VerifyRequest{Email: testEmail, Code: code})
+	require.Equal(t, status, resp.Code)
+
+	var body models.VerificationErrorResponse
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+
+	return body
+}
+
+func TestVerifyRegistrationNotFound(t *testing.T) {
+	router, _ := newRegistrationRouter()
+
+	body := verify(t, router, testCode, http.StatusNotFound)
+	require.Equal(t, RegistrationStatusNotFound, body.Status)
+}
+
+func TestVerifyRegistrationCodeExpired(t *testing.T) {
+	router, mem := newRegistrationRouter()
+	pendingRegistration(t, mem, testCode)
+
+	mem.advance(verificationCodeTTL)
+
+	body := verify(t, router, testCode, http.StatusForbidden)
+	require.Equal(t, RegistrationStatusCodeExpired, body.Status)
+	require.Equal(t, ErrCodeExpired.Error(), body.Error)
+}
+
+func TestVerifyRegistrationCodeIncorrect(t *testing.T) {
+	router, mem := newRegistrationRouter()
+	pendingRegistration(t, mem, testCode)
+
+	body := verify(t, router, "654321", http.StatusForbidden)
+	require.Equal(t, RegistrationStatusCodeIncorrect, body.Status)
+	require.Equal(t, maxCodeAttempts-1, body.AttemptsLeft)
+
+	// the right code is still accepted
+	resp := postJSON(router, "/auth/verify", models.VerifyRequest{Email: testEmail, Code: testCode})
+	require.Equal(t, http.StatusCreated, resp.Code)
+
+	_, err := mem.Get(PendingUserKey + testEmail)
+	require.Error(t, err)
+}
+
+func TestVerifyRegistrationTooManyAttempts(t *testing.T) {
+	router, mem := newRegistrationRouter()
+	pendingRegistration(t, mem, testCode)
+
+	for i := 1; i < maxCodeAttempts; i++ {
+		body := verify(t, router, "654321", http.StatusForbidden)
+		require.Equal(t, RegistrationStatusCodeIncorrect, body.Status)
+		require.Equal(t, maxCodeAttempts-i, body.AttemptsLeft)
+
+		// wait out the delay of the brute-force protection
+		mem.advance(10 * time.Second)
+	}
+
+	body := verify(t, router, "654321", http.StatusForbidden)
+	require.Equal(t, RegistrationStatusTooManyAttempts, body.Status)
+	mem.advance(10 * time.Second)
+
+	// the code is burnt, even the right one is rejected until a new one is sent
+	body = verify(t, router, testCode, http.StatusForbidden)
+	require.Equal(t, RegistrationStatusTooManyAttempts, body.Status)
+	require.Equal(t, ErrTooManyCodeAttempts.Error(), body.Error)
+}
+
+func TestResendCodeCooldown(t *testing.T) {
+	router, mem := newRegistrationRouter()
+	pendingRegistration(t, mem, testCode)
+
+	codes := make(chan *httptest.ResponseRecorder, 5)
+	var wg sync.WaitGroup
+	for i := 0; i < cap(codes); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes <- postJSON(router, "/auth/resend-code", models.ResendCodeRequest{Email: testEmail})
+		}()
+	}
+	wg.Wait()
+	close(codes)
+
+	sent := 0
+	for resp := range codes {
+		if resp.Code == http.StatusOK {
+			sent++
+			continue
+		}
+
+		require.Equal(t, http.StatusTooManyRequests, resp.Code)
+		require.Equal(t, "60", resp.Header().Get("Retry-After"))
+	}
+	require.Equal(t, 1, sent)
+
+	mem.advance(resendCodeCooldown)
+
+	resp := postJSON(router, "/auth/resend-code", models.ResendCodeRequest{Email: testEmail})
+	require.Equal(t, http.StatusOK, resp.Code)
+}
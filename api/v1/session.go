@@ -0,0 +1,146 @@
+package v1
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/mirasildev/note_project/api/models"
+	"github.com/mirasildev/note_project/storage"
+	"github.com/mirasildev/note_project/storage/repo"
+)
+
+const SessionSeenKey = "session_seen_"
+
+var ErrSessionNotFound = errors.New("session not found")
+
+// @Security ApiKeyAuth
+// @Router /me/sessions [get]
+// @Summary Get sessions
+// @Description Get the devices the authenticated user is signed in on
+// @Tags sessions
+// @Accept json
+// @Produce json
+// @Success 200 {object} models.GetAllSessionsResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) GetSessions(c *gin.Context) {
+	payload, err := h.GetAuthPayload(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	sessions, err := h.storage.Session().GetAll(payload.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	response := models.GetAllSessionsResponse{
+		Sessions: make([]*models.Session, 0),
+	}
+	for _, s := range sessions {
+		session := parseSessionModel(s)
+		session.Current = s.ID == payload.SessionID
+		response.Sessions = append(response.Sessions, &session)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func parseSessionModel(s *repo.Session) models.Session {
+	return models.Session{
+		ID:         s.ID,
+		ClientID:   s.ClientID,
+		UserAgent:  s.UserAgent,
+		IPAddress:  s.IPAddress,
+		CreatedAt:  s.CreatedAt,
+		LastSeenAt: s.LastSeenAt,
+		ExpiresAt:  s.ExpiresAt,
+	}
+}
+
+// @Security ApiKeyAuth
+// @Router /me/sessions/{id} [delete]
+// @Summary Revoke a session
+// @Description Sign out the device of the session. Its access and refresh tokens stop working
+// @Tags sessions
+// @Accept json
+// @Produce json
+// @Param id path string true "ID"
+// @Success 200 {object} models.ResponseOK
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) DeleteSession(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := h.GetAuthPayload(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	session, err := h.storage.Session().Get(id.String())
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, errorResponse(ErrSessionNotFound))
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if session.UserID != payload.UserID || session.RevokedAt != nil {
+		c.JSON(http.StatusNotFound, errorResponse(ErrSessionNotFound))
+		return
+	}
+
+	err = h.revokeRefreshTokenFamily(session.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	h.audit(c, repo.AuditEventTokenRevoked, payload.UserID, payload.Email)
+
+	c.JSON(http.StatusOK, models.ResponseOK{
+		Message: "Successfully revoked!",
+	})
+}
+
+// checkSession rejects tokens of a session which was revoked or has expired.
+// A session is active for as long as its refresh token family is.
+func (h *handlerV1) checkSession(sessionID string) error {
+	_, err := h.inMemory.Get(RefreshTokenFamilyKey + sessionID)
+	if errors.Is(err, storage.ErrKeyNotFound) {
+		return ErrTokenRevoked
+	}
+	if err != nil {
+		return err
+	}
+
+	return h.touchSession(sessionID)
+}
+
+// touchSession updates last_seen_at of the session at most once per lastUsedInterval
+func (h *handlerV1) touchSession(sessionID string) error {
+	firstUse, err := h.inMemory.SetNX(SessionSeenKey+sessionID, "1", lastUsedInterval)
+	if err != nil {
+		return err
+	}
+
+	if !firstUse {
+		return nil
+	}
+
+	return h.storage.Session().UpdateLastSeen(sessionID, time.Now())
+}
@@ -0,0 +1,235 @@
+package v1
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mirasildev/note_project/api/models"
+	"github.com/mirasildev/note_project/storage/repo"
+)
+
+const (
+	tagsMatchAny = "any"
+	tagsMatchAll = "all"
+)
+
+var (
+	ErrTagNotFound      = errors.New("tag not found")
+	ErrTagExists        = errors.New("tag already exists, merge the tags instead")
+	ErrTagNameEmpty     = errors.New("tag name can't be empty")
+	ErrMergeSameTag     = errors.New("can't merge a tag into itself")
+	ErrInvalidTagsMatch = errors.New("tags_match must be any or all")
+)
+
+// normalizeTags trims and lowercases the tag names, so that "Work"
+// and "work " are the same tag, and drops empty and repeated names
+func normalizeTags(tags []string) []string {
+	result := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+
+	for _, t := range tags {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t == "" || seen[t] {
+			continue
+		}
+
+		seen[t] = true
+		result = append(result, t)
+	}
+
+	return result
+}
+
+func parseTagModel(t *repo.Tag) models.Tag {
+	return models.Tag{
+		ID:        t.ID,
+		Name:      t.Name,
+		NoteCount: t.NoteCount,
+		CreatedAt: t.CreatedAt,
+	}
+}
+
+// @Security ApiKeyAuth
+// @Router /tags [get]
+// @Summary Get tags
+// @Description Get the tags of the authenticated user with the number of notes of each tag
+// @Tags tags
+// @Accept json
+// @Produce json
+// @Success 200 {object} models.GetAllTagsResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) GetTags(c *gin.Context) {
+	payload, err := h.GetAuthPayload(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	tags, err := h.storage.Tag().GetAll(payload.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	response := models.GetAllTagsResponse{
+		Tags: make([]*models.Tag, 0),
+	}
+	for _, t := range tags {
+		p := parseTagModel(t)
+		response.Tags = append(response.Tags, &p)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// @Security ApiKeyAuth
+// @Router /tags/{id} [put]
+// @Summary Rename a tag
+// @Description Rename a tag. Renaming to the name of another tag fails, merge the tags instead
+// @Tags tags
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Param data body models.RenameTagRequest true "Data"
+// @Success 200 {object} models.Tag
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) RenameTag(c *gin.Context) {
+	var req models.RenameTagRequest
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := h.GetAuthPayload(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	names := normalizeTags([]string{req.Name})
+	if len(names) == 0 {
+		c.JSON(http.StatusBadRequest, errorResponse(ErrTagNameEmpty))
+		return
+	}
+
+	tag, err := h.storage.Tag().Rename(id, payload.UserID, names[0])
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, errorResponse(ErrTagNotFound))
+			return
+		}
+
+		if errors.Is(err, repo.ErrTagNameTaken) {
+			c.JSON(http.StatusConflict, errorResponse(ErrTagExists))
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, parseTagModel(tag))
+}
+
+// @Security ApiKeyAuth
+// @Router /tags/{id}/merge [post]
+// @Summary Merge tags
+// @Description Move the notes of the tag to the target tag and delete the tag
+// @Tags tags
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Param data body models.MergeTagRequest true "Data"
+// @Success 200 {object} models.Tag
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) MergeTag(c *gin.Context) {
+	var req models.MergeTagRequest
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if id == req.TargetID {
+		c.JSON(http.StatusBadRequest, errorResponse(ErrMergeSameTag))
+		return
+	}
+
+	payload, err := h.GetAuthPayload(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	tag, err := h.storage.Tag().Merge(id, req.TargetID, payload.UserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, errorResponse(ErrTagNotFound))
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, parseTagModel(tag))
+}
+
+// @Security ApiKeyAuth
+// @Router /tags/{id} [delete]
+// @Summary Delete a tag
+// @Description Delete a tag and remove it from the notes
+// @Tags tags
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Success 200 {object} models.ResponseOK
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) DeleteTag(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := h.GetAuthPayload(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	err = h.storage.Tag().Delete(id, payload.UserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, errorResponse(ErrTagNotFound))
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.ResponseOK{
+		Message: "Successfully deleted!",
+	})
+}
@@ -0,0 +1,35 @@
+package v1
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mirasildev/note_project/pkg/utils"
+	"github.com/mirasildev/note_project/storage/repo"
+)
+
+// takenTags fails every rename like the unique index does
+// when a tag with the name was created meanwhile
+type takenTags struct {
+	repo.TagStorageI
+}
+
+func (t *takenTags) Rename(id, userID int64, name string) (*repo.Tag, error) {
+	return nil, repo.ErrTagNameTaken
+}
+
+func TestRenameTagToTakenName(t *testing.T) {
+	h := newTestHandler(&fakeStorage{tags: &takenTags{}})
+
+	router := gin.New()
+	router.PUT("/tags/:id", authorized(&utils.Payload{UserID: 1}, h.RenameTag))
+
+	req := httptest.NewRequest(http.MethodPut, "/tags/1", strings.NewReader(`{"name": "work"}`))
+	req.Header.Set("Content-Type", "application/json")
+
+	requireError(t, performRequest(router, req), http.StatusConflict, ErrTagExists)
+}
@@ -0,0 +1,232 @@
+package v1
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/mirasildev/note_project/api/models"
+	"github.com/mirasildev/note_project/pkg/utils"
+	"github.com/mirasildev/note_project/storage"
+	"github.com/mirasildev/note_project/storage/repo"
+)
+
+const (
+	RefreshTokenKey       = "refresh_token_"
+	RefreshTokenUsedKey   = "refresh_token_used_"
+	RefreshTokenFamilyKey = "refresh_token_family_"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("refresh token is invalid")
+	ErrRefreshTokenReused  = errors.New("refresh token has already been used")
+)
+
+// refreshTokenData is what is stored for every issued refresh token.
+// All tokens rotated from one login share the same family.
+type refreshTokenData struct {
+	UserID    int64     `json:"user_id"`
+	FamilyID  string    `json:"family_id"`
+	IssuedAt  time.Time `json:"issued_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	// ClientID and Scopes are set for families issued to OAuth clients
+	ClientID string   `json:"client_id,omitempty"`
+	Scopes   []string `json:"scopes,omitempty"`
+}
+
+// newAuthResponse starts a new session for the client of the request and issues
+// an access token and a refresh token of a new family. The family id is the session id.
+func (h *handlerV1) newAuthResponse(c *gin.Context, user *repo.User) (*models.AuthResponse, error) {
+	return h.startSession(c, user, "", nil)
+}
+
+// startSession is newAuthResponse for tokens which may be limited
+// to an OAuth client and scopes
+func (h *handlerV1) startSession(c *gin.Context, user *repo.User, clientID string, scopes []string) (*models.AuthResponse, error) {
+	familyID, err := uuid.NewRandom()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	family := &refreshTokenData{
+		UserID:    user.ID,
+		FamilyID:  familyID.String(),
+		IssuedAt:  now,
+		ExpiresAt: now.Add(h.cfg.RefreshTokenDuration),
+		ClientID:  clientID,
+		Scopes:    scopes,
+	}
+
+	session := &repo.Session{
+		ID:        family.FamilyID,
+		UserID:    user.ID,
+		UserAgent: c.Request.UserAgent(),
+		IPAddress: c.ClientIP(),
+		ExpiresAt: family.ExpiresAt,
+	}
+	if clientID != "" {
+		session.ClientID = &clientID
+	}
+
+	_, err = h.storage.Session().Create(session)
+	if err != nil {
+		return nil, err
+	}
+
+	err = h.inMemory.Set(RefreshTokenFamilyKey+family.FamilyID, "active", h.cfg.RefreshTokenDuration)
+	if err != nil {
+		return nil, err
+	}
+
+	return h.issueAuthTokens(user, family)
+}
+
+// issueAuthTokens creates an access token and the next refresh token of the family
+func (h *handlerV1) issueAuthTokens(user *repo.User, family *refreshTokenData) (*models.AuthResponse, error) {
+	accessToken, payload, err := utils.CreateToken(h.cfg, &utils.TokenParams{
+		UserID:    user.ID,
+		Email:     user.Email,
+		Role:      user.Role,
+		Scopes:    family.Scopes,
+		SessionID: family.FamilyID,
+		ClientID:  family.ClientID,
+		Duration:  h.cfg.AccessTokenDuration,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(family)
+	if err != nil {
+		return nil, err
+	}
+
+	err = h.inMemory.Set(RefreshTokenKey+utils.HashToken(refreshToken), string(data), time.Until(family.ExpiresAt))
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.AuthResponse{
+		ID:           user.ID,
+		FirstName:    user.FirstName,
+		LastName:     user.LastName,
+		Email:        user.Email,
+		CreatedAt:    user.CreatedAt,
+		AccessToken:  accessToken,
+		ExpiresAt:    payload.ExpiredAt,
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+// getRefreshTokenFamily returns the family a refresh token belongs to
+func (h *handlerV1) getRefreshTokenFamily(refreshToken string) (*refreshTokenData, error) {
+	data, err := h.inMemory.Get(RefreshTokenKey + utils.HashToken(refreshToken))
+	if errors.Is(err, storage.ErrKeyNotFound) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var family refreshTokenData
+	err = json.Unmarshal([]byte(data), &family)
+	if err != nil {
+		return nil, err
+	}
+
+	return &family, nil
+}
+
+// rotateRefreshToken exchanges a refresh token issued to the client for a new one
+// of the same family. Presenting a token that was already rotated revokes the whole family.
+func (h *handlerV1) rotateRefreshToken(refreshToken, clientID string) (*models.AuthResponse, error) {
+	tokenHash := utils.HashToken(refreshToken)
+
+	family, err := h.getRefreshTokenFamily(refreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if family.ClientID != clientID {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	_, err = h.inMemory.Get(RefreshTokenFamilyKey + family.FamilyID)
+	if errors.Is(err, storage.ErrKeyNotFound) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	firstUse, err := h.inMemory.SetNX(RefreshTokenUsedKey+tokenHash, "1", time.Until(family.ExpiresAt))
+	if err != nil {
+		return nil, err
+	}
+
+	if !firstUse {
+		err = h.revokeRefreshTokenFamily(family.FamilyID)
+		if err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	revoked, err := h.isTokenRevoked(family.UserID, family.IssuedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if revoked {
+		return nil, ErrTokenRevoked
+	}
+
+	err = h.touchSession(family.FamilyID)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := h.storage.User().Get(family.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	return h.issueAuthTokens(user, family)
+}
+
+// revokeRefreshToken revokes the family of a refresh token owned by the user.
+// Unknown tokens are ignored since there is nothing left to revoke.
+func (h *handlerV1) revokeRefreshToken(userID int64, refreshToken string) error {
+	family, err := h.getRefreshTokenFamily(refreshToken)
+	if errors.Is(err, ErrInvalidRefreshToken) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if family.UserID != userID {
+		return nil
+	}
+
+	return h.revokeRefreshTokenFamily(family.FamilyID)
+}
+
+// revokeRefreshTokenFamily ends the session of the family,
+// its access tokens are rejected from now on as well
+func (h *handlerV1) revokeRefreshTokenFamily(familyID string) error {
+	err := h.inMemory.Delete(RefreshTokenFamilyKey + familyID)
+	if err != nil {
+		return err
+	}
+
+	return h.storage.Session().Revoke(familyID)
+}
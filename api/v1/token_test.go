@@ -0,0 +1,97 @@
+package v1
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mirasildev/note_project/api/models"
+	"github.com/mirasildev/note_project/storage/repo"
+)
+
+// fakeSessions remembers which sessions were revoked
+type fakeSessions struct {
+	repo.SessionStorageI
+	revoked map[string]bool
+}
+
+func (s *fakeSessions) Create(session *repo.Session) (*repo.Session, error) {
+	return session, nil
+}
+
+func (s *fakeSessions) UpdateLastSeen(id string, lastSeenAt time.Time) error {
+	return nil
+}
+
+func (s *fakeSessions) Revoke(id string) error {
+	s.revoked[id] = true
+	return nil
+}
+
+func newTokenHandler() (*handlerV1, *fakeSessions) {
+	sessions := &fakeSessions{revoked: make(map[string]bool)}
+
+	h := newTestHandler(&fakeStorage{
+		users:    &fakeUsers{},
+		sessions: sessions,
+	})
+	h.cfg.AuthSecretKey = "secret"
+	h.cfg.AccessTokenDuration = time.Minute
+	h.cfg.RefreshTokenDuration = time.Hour
+
+	return h, sessions
+}
+
+func startTestSession(t *testing.T, h *handlerV1, clientID string) *models.AuthResponse {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/auth/login", nil)
+
+	resp, err := h.startSession(c, &repo.User{ID: 1, Email: "john@example.com"}, clientID, nil)
+	require.NoError(t, err)
+
+	return resp
+}
+
+func TestRefreshTokenReuseRevokesFamily(t *testing.T) {
+	h, sessions := newTokenHandler()
+
+	first := startTestSession(t, h, "")
+	family, err := h.getRefreshTokenFamily(first.RefreshToken)
+	require.NoError(t, err)
+
+	second, err := h.rotateRefreshToken(first.RefreshToken, "")
+	require.NoError(t, err)
+	require.NotEqual(t, first.RefreshToken, second.RefreshToken)
+	require.False(t, sessions.revoked[family.FamilyID])
+
+	// the rotated token was stolen and is presented again
+	_, err = h.rotateRefreshToken(first.RefreshToken, "")
+	require.ErrorIs(t, err, ErrRefreshTokenReused)
+	require.True(t, sessions.revoked[family.FamilyID])
+
+	// the token the legitimate client holds is revoked with its family
+	_, err = h.rotateRefreshToken(second.RefreshToken, "")
+	require.ErrorIs(t, err, ErrInvalidRefreshToken)
+}
+
+func TestRefreshTokenOfOtherClient(t *testing.T) {
+	h, sessions := newTokenHandler()
+
+	resp := startTestSession(t, h, "client-a")
+
+	_, err := h.rotateRefreshToken(resp.RefreshToken, "client-b")
+	require.ErrorIs(t, err, ErrInvalidRefreshToken)
+
+	_, err = h.rotateRefreshToken(resp.RefreshToken, "")
+	require.ErrorIs(t, err, ErrInvalidRefreshToken)
+
+	// rejecting the other clients neither uses up the token nor revokes the family
+	require.Empty(t, sessions.revoked)
+
+	_, err = h.rotateRefreshToken(resp.RefreshToken, "client-a")
+	require.NoError(t, err)
+}
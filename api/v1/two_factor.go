@@ -0,0 +1,530 @@
+package v1
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pquerna/otp/totp"
+
+	"github.com/mirasildev/note_project/api/models"
+	"github.com/mirasildev/note_project/pkg/utils"
+	"github.com/mirasildev/note_project/storage"
+	"github.com/mirasildev/note_project/storage/repo"
+)
+
+const (
+	TwoFactorChallengeKey     = "two_factor_challenge_"
+	TwoFactorChallengeUsedKey = "two_factor_challenge_used_"
+	TwoFactorUsedCodeKey      = "two_factor_used_code_"
+)
+
+const (
+	totpIssuer             = "Note Project"
+	twoFactorChallengeTTL  = 5 * time.Minute
+	recoveryCodesCount     = 10
+	recoveryCodeByteLength = 5
+)
+
+var (
+	ErrTwoFactorEnabled     = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnabled  = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorNotEnrolled = errors.New("two-factor enrollment has not been started")
+	ErrInvalidChallenge     = errors.New("two-factor challenge is invalid or has expired")
+)
+
+// @Security ApiKeyAuth
+// @Router /auth/2fa/enroll [post]
+// @Summary Enroll in two-factor authentication
+// @Description Generate a new TOTP secret. It is activated by /auth/2fa/confirm
+// @Tags two-factor
+// @Accept json
+// @Produce json
+// @Success 200 {object} models.TwoFactorEnrollResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) EnrollTwoFactor(c *gin.Context) {
+	payload, err := h.GetAuthPayload(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	twoFactor, err := h.storage.TwoFactor().Get(payload.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if twoFactor.Enabled {
+		c.JSON(http.StatusBadRequest, errorResponse(ErrTwoFactorEnabled))
+		return
+	}
+
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      totpIssuer,
+		AccountName: payload.Email,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = h.storage.TwoFactor().SetSecret(payload.UserID, key.Secret())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.TwoFactorEnrollResponse{
+		Secret:     key.Secret(),
+		OtpauthURI: key.URL(),
+	})
+}
+
+// @Security ApiKeyAuth
+// @Router /auth/2fa/confirm [post]
+// @Summary Confirm two-factor authentication
+// @Description Enable two-factor authentication with a code from the authenticator app
+// @Tags two-factor
+// @Accept json
+// @Produce json
+// @Param data body models.TwoFactorCodeRequest true "Data"
+// @Success 200 {object} models.RecoveryCodesResponse
+// @Failure 429 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) ConfirmTwoFactor(c *gin.Context) {
+	var req models.TwoFactorCodeRequest
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := h.GetAuthPayload(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if !h.checkAttempts(c, twoFactorAction, payload.Email) {
+		return
+	}
+
+	twoFactor, err := h.storage.TwoFactor().Get(payload.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if twoFactor.Enabled {
+		c.JSON(http.StatusBadRequest, errorResponse(ErrTwoFactorEnabled))
+		return
+	}
+
+	if twoFactor.Secret == nil {
+		c.JSON(http.StatusBadRequest, errorResponse(ErrTwoFactorNotEnrolled))
+		return
+	}
+
+	ok, err := h.validateTOTP(payload.UserID, *twoFactor.Secret, req.Code)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if !ok {
+		err = h.registerFailedAttempt(c, twoFactorAction, payload.Email)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		c.JSON(http.StatusForbidden, errorResponse(ErrIncorrectCode))
+		return
+	}
+
+	err = h.resetAttempts(twoFactorAction, payload.Email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = h.storage.TwoFactor().Enable(payload.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	codes, err := h.regenerateRecoveryCodes(payload.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.RecoveryCodesResponse{
+		RecoveryCodes: codes,
+	})
+}
+
+// @Security ApiKeyAuth
+// @Router /auth/2fa/disable [post]
+// @Summary Disable two-factor authentication
+// @Description Disable two-factor authentication with an authenticator or recovery code
+// @Tags two-factor
+// @Accept json
+// @Produce json
+// @Param data body models.TwoFactorCodeRequest true "Data"
+// @Success 200 {object} models.ResponseOK
+// @Failure 429 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) DisableTwoFactor(c *gin.Context) {
+	var req models.TwoFactorCodeRequest
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := h.GetAuthPayload(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if !h.checkAttempts(c, twoFactorAction, payload.Email) {
+		return
+	}
+
+	err = h.checkSecondFactor(payload.UserID, req.Code)
+	if err != nil {
+		if errors.Is(err, ErrIncorrectCode) {
+			err = h.registerFailedAttempt(c, twoFactorAction, payload.Email)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, errorResponse(err))
+				return
+			}
+
+			c.JSON(http.StatusForbidden, errorResponse(ErrIncorrectCode))
+			return
+		}
+
+		if errors.Is(err, ErrTwoFactorNotEnabled) {
+			c.JSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = h.resetAttempts(twoFactorAction, payload.Email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = h.storage.TwoFactor().Disable(payload.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.ResponseOK{
+		Message: "Two-factor authentication has been disabled!",
+	})
+}
+
+// @Security ApiKeyAuth
+// @Router /auth/2fa/recovery-codes [post]
+// @Summary Regenerate recovery codes
+// @Description Replace all recovery codes. Requires a code from the authenticator app
+// @Tags two-factor
+// @Accept json
+// @Produce json
+// @Param data body models.TwoFactorCodeRequest true "Data"
+// @Success 200 {object} models.RecoveryCodesResponse
+// @Failure 429 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) RegenerateRecoveryCodes(c *gin.Context) {
+	var req models.TwoFactorCodeRequest
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := h.GetAuthPayload(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if !h.checkAttempts(c, twoFactorAction, payload.Email) {
+		return
+	}
+
+	twoFactor, err := h.storage.TwoFactor().Get(payload.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if !twoFactor.Enabled {
+		c.JSON(http.StatusBadRequest, errorResponse(ErrTwoFactorNotEnabled))
+		return
+	}
+
+	ok, err := h.validateTOTP(payload.UserID, *twoFactor.Secret, req.Code)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if !ok {
+		err = h.registerFailedAttempt(c, twoFactorAction, payload.Email)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		c.JSON(http.StatusForbidden, errorResponse(ErrIncorrectCode))
+		return
+	}
+
+	err = h.resetAttempts(twoFactorAction, payload.Email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	codes, err := h.regenerateRecoveryCodes(payload.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.RecoveryCodesResponse{
+		RecoveryCodes: codes,
+	})
+}
+
+// @Router /auth/2fa/verify [post]
+// @Summary Verify two-factor login
+// @Description Exchange the challenge returned by login and a second factor code for tokens.
+// @Description A challenge can be tried once, after a wrong code the user signs in again
+// @Tags two-factor
+// @Accept json
+// @Produce json
+// @Param data body models.TwoFactorVerifyRequest true "Data"
+// @Success 200 {object} models.AuthResponse
+// @Failure 429 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) VerifyTwoFactor(c *gin.Context) {
+	var req models.TwoFactorVerifyRequest
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	challengeHash := utils.HashToken(req.ChallengeToken)
+	challengeKey := TwoFactorChallengeKey + challengeHash
+
+	userID, err := h.inMemory.Get(challengeKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrKeyNotFound) {
+			c.JSON(http.StatusUnauthorized, errorResponse(ErrInvalidChallenge))
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	id, err := strconv.ParseInt(userID, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	user, err := h.storage.User().Get(id)
+	if err != nil {
+		// the account was purged since the challenge was issued
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusUnauthorized, errorResponse(ErrInvalidChallenge))
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if !h.checkAttempts(c, twoFactorAction, user.Email) {
+		return
+	}
+
+	// only one request gets to check a code against the challenge,
+	// otherwise concurrent requests could each sign in with another recovery code
+	firstUse, err := h.inMemory.SetNX(TwoFactorChallengeUsedKey+challengeHash, "1", twoFactorChallengeTTL)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if !firstUse {
+		c.JSON(http.StatusUnauthorized, errorResponse(ErrInvalidChallenge))
+		return
+	}
+
+	err = h.inMemory.Delete(challengeKey)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = h.checkSecondFactor(id, req.Code)
+	if err != nil {
+		if errors.Is(err, ErrIncorrectCode) {
+			h.audit(c, repo.AuditEventLoginFailed, user.ID, user.Email)
+
+			err = h.registerFailedAttempt(c, twoFactorAction, user.Email)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, errorResponse(err))
+				return
+			}
+
+			c.JSON(http.StatusForbidden, errorResponse(ErrIncorrectCode))
+			return
+		}
+
+		if errors.Is(err, ErrTwoFactorNotEnabled) {
+			c.JSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = h.resetAttempts(twoFactorAction, user.Email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp, err := h.newAuthResponse(c, user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	h.audit(c, repo.AuditEventLoginSucceeded, user.ID, user.Email)
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// newTwoFactorChallenge returns a short-lived challenge which is exchanged
+// for tokens by /auth/2fa/verify
+func (h *handlerV1) newTwoFactorChallenge(userID int64) (*models.TwoFactorChallengeResponse, error) {
+	challenge, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	err = h.inMemory.Set(
+		TwoFactorChallengeKey+utils.HashToken(challenge),
+		strconv.FormatInt(userID, 10),
+		twoFactorChallengeTTL,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TwoFactorChallengeResponse{
+		TwoFactorRequired: true,
+		ChallengeToken:    challenge,
+		ExpiresAt:         time.Now().Add(twoFactorChallengeTTL),
+	}, nil
+}
+
+// checkSecondFactor accepts either a TOTP code or an unused recovery code
+func (h *handlerV1) checkSecondFactor(userID int64, code string) error {
+	twoFactor, err := h.storage.TwoFactor().Get(userID)
+	if err != nil {
+		return err
+	}
+
+	if !twoFactor.Enabled {
+		return ErrTwoFactorNotEnabled
+	}
+
+	ok, err := h.validateTOTP(userID, *twoFactor.Secret, code)
+	if err != nil {
+		return err
+	}
+
+	if ok {
+		return nil
+	}
+
+	err = h.storage.TwoFactor().UseRecoveryCode(userID, utils.HashToken(normalizeRecoveryCode(code)))
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrIncorrectCode
+	}
+
+	return err
+}
+
+// validateTOTP checks the code and makes sure it can't be replayed
+// while it is still inside the validity window
+func (h *handlerV1) validateTOTP(userID int64, secret, code string) (bool, error) {
+	if !totp.Validate(code, secret) {
+		return false, nil
+	}
+
+	return h.inMemory.SetNX(
+		TwoFactorUsedCodeKey+strconv.FormatInt(userID, 10)+"_"+code,
+		"1",
+		2*time.Minute,
+	)
+}
+
+func (h *handlerV1) regenerateRecoveryCodes(userID int64) ([]string, error) {
+	codes := make([]string, 0, recoveryCodesCount)
+	hashes := make([]string, 0, recoveryCodesCount)
+
+	for i := 0; i < recoveryCodesCount; i++ {
+		b := make([]byte, recoveryCodeByteLength)
+		_, err := rand.Read(b)
+		if err != nil {
+			return nil, err
+		}
+
+		code := hex.EncodeToString(b)
+		code = code[:len(code)/2] + "-" + code[len(code)/2:]
+
+		codes = append(codes, code)
+		hashes = append(hashes, utils.HashToken(normalizeRecoveryCode(code)))
+	}
+
+	err := h.storage.TwoFactor().ReplaceRecoveryCodes(userID, hashes)
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+}
@@ -0,0 +1,167 @@
+package v1
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mirasildev/note_project/api/models"
+	"github.com/mirasildev/note_project/pkg/utils"
+	"github.com/mirasildev/note_project/storage/repo"
+)
+
+var testRecoveryCodes = []string{"aaaaa-11111", "bbbbb-22222", "ccccc-33333", "ddddd-44444"}
+
+// fakeTwoFactor has two-factor enabled for every user with the test recovery codes
+type fakeTwoFactor struct {
+	repo.TwoFactorStorageI
+
+	mu     sync.Mutex
+	hashes map[string]bool
+}
+
+func newFakeTwoFactor() *fakeTwoFactor {
+	hashes := make(map[string]bool)
+	for _, code := range testRecoveryCodes {
+		hashes[utils.HashToken(normalizeRecoveryCode(code))] = true
+	}
+
+	return &fakeTwoFactor{hashes: hashes}
+}
+
+func (f *fakeTwoFactor) Get(userID int64) (*repo.TwoFactor, error) {
+	secret := "JBSWY3DPEHPK3PXP"
+	return &repo.TwoFactor{UserID: userID, Secret: &secret, Enabled: true}, nil
+}
+
+func (f *fakeTwoFactor) UseRecoveryCode(userID int64, codeHash string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !f.hashes[codeHash] {
+		return sql.ErrNoRows
+	}
+
+	delete(f.hashes, codeHash)
+	return nil
+}
+
+// missingUsers has no users, like after the account was purged
+type missingUsers struct {
+	fakeUsers
+}
+
+func (u *missingUsers) Get(id int64) (*repo.User, error) {
+	return nil, sql.ErrNoRows
+}
+
+func newTwoFactorRouter(users repo.UserStorageI) (*gin.Engine, *handlerV1) {
+	h := newTestHandler(&fakeStorage{
+		users:     users,
+		sessions:  &fakeSessions{revoked: make(map[string]bool)},
+		twoFactor: newFakeTwoFactor(),
+		auditLogs: &fakeAuditLogs{},
+	})
+	h.cfg.AuthSecretKey = "secret"
+	h.cfg.AccessTokenDuration = time.Minute
+	h.cfg.RefreshTokenDuration = time.Hour
+
+	router := gin.New()
+	router.POST("/auth/2fa/verify", h.VerifyTwoFactor)
+
+	return router, h
+}
+
+func verifyRequest(t *testing.T, challenge, code string) *http.Request {
+	body, err := json.Marshal(models.TwoFactorVerifyRequest{
+		ChallengeToken: challenge,
+		Code:           code,
+	})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/2fa/verify", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+
+	return req
+}
+
+func requireError(t *testing.T, resp *httptest.ResponseRecorder, code int, err error) {
+	require.Equal(t, code, resp.Code)
+
+	var body models.ErrorResponse
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+	require.Equal(t, err.Error(), body.Error)
+}
+
+func TestVerifyTwoFactorChallengeIsUsedOnce(t *testing.T) {
+	router, h := newTwoFactorRouter(&fakeUsers{})
+
+	challenge, err := h.newTwoFactorChallenge(1)
+	require.NoError(t, err)
+
+	resp := performRequest(router, verifyRequest(t, challenge.ChallengeToken, testRecoveryCodes[0]))
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	resp = performRequest(router, verifyRequest(t, challenge.ChallengeToken, testRecoveryCodes[1]))
+	requireError(t, resp, http.StatusUnauthorized, ErrInvalidChallenge)
+}
+
+func TestVerifyTwoFactorConcurrently(t *testing.T) {
+	router, h := newTwoFactorRouter(&fakeUsers{})
+
+	challenge, err := h.newTwoFactorChallenge(1)
+	require.NoError(t, err)
+
+	codes := make(chan int, len(testRecoveryCodes))
+	var wg sync.WaitGroup
+	for _, code := range testRecoveryCodes {
+		wg.Add(1)
+		go func(code string) {
+			defer wg.Done()
+			codes <- performRequest(router, verifyRequest(t, challenge.ChallengeToken, code)).Code
+		}(code)
+	}
+	wg.Wait()
+	close(codes)
+
+	signedIn := 0
+	for code := range codes {
+		if code == http.StatusOK {
+			signedIn++
+		} else {
+			require.Equal(t, http.StatusUnauthorized, code)
+		}
+	}
+	require.Equal(t, 1, signedIn)
+}
+
+func TestVerifyTwoFactorWrongCodeUsesChallenge(t *testing.T) {
+	router, h := newTwoFactorRouter(&fakeUsers{})
+
+	challenge, err := h.newTwoFactorChallenge(1)
+	require.NoError(t, err)
+
+	resp := performRequest(router, verifyRequest(t, challenge.ChallengeToken, "wrong-code"))
+	requireError(t, resp, http.StatusForbidden, ErrIncorrectCode)
+
+	resp = performRequest(router, verifyRequest(t, challenge.ChallengeToken, testRecoveryCodes[0]))
+	requireError(t, resp, http.StatusUnauthorized, ErrInvalidChallenge)
+}
+
+func TestVerifyTwoFactorOfPurgedUser(t *testing.T) {
+	router, h := newTwoFactorRouter(&missingUsers{})
+
+	challenge, err := h.newTwoFactorChallenge(1)
+	require.NoError(t, err)
+
+	resp := performRequest(router, verifyRequest(t, challenge.ChallengeToken, testRecoveryCodes[0]))
+	requireError(t, resp, http.StatusUnauthorized, ErrInvalidChallenge)
+}
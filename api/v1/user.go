@@ -1,11 +1,14 @@
 package v1
 
 import (
+	"database/sql"
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/mirasildev/note_project/api/models"
+	"github.com/mirasildev/note_project/pkg/utils"
 	"github.com/mirasildev/note_project/storage/repo"
 )
 
@@ -30,12 +33,30 @@ func (h *handlerV1) CreateUser(c *gin.Context) {
 		return
 	}
 
+	if !h.validatePassword(c, req.Password) {
+		return
+	}
+
+	_, err = h.storage.User().GetByEmail(req.Email)
+	if !errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusBadRequest, errorResponse(ErrEmailExists))
+		return
+	}
+
+	hashedPassword, err := utils.HashPassword(h.cfg, req.Password)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
 	resp, err := h.storage.User().Create(&repo.User{
 		FirstName:   req.FirstName,
 		LastName:    req.LastName,
 		PhoneNumber: req.PhoneNumber,
 		Email:       req.Email,
+		Password:    hashedPassword,
 		ImageURL:    req.ImageURL,
+		Role:        req.Role,
 	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, errorResponse(err))
@@ -53,10 +74,22 @@ func parseUserModel(user *repo.User) models.User {
 		PhoneNumber: user.PhoneNumber,
 		Email:       user.Email,
 		ImageURL:    user.ImageURL,
+		Role:        user.Role,
 		CreatedAt:   user.CreatedAt,
 	}
 }
 
+// canAccessUser reports whether the authenticated user may read or edit
+// the profile of the user with the given id
+func (h *handlerV1) canAccessUser(c *gin.Context, id int64) bool {
+	payload, err := h.GetAuthPayload(c)
+	if err != nil {
+		return false
+	}
+
+	return payload.Role == repo.UserRoleAdmin || payload.UserID == id
+}
+
 // @Security ApiKeyAuth
 // @Router /users/{id} [get]
 // @Summary Get user by id
@@ -74,8 +107,18 @@ func (h *handlerV1) GetUser(c *gin.Context) {
 		return
 	}
 
+	if !h.canAccessUser(c, int64(id)) {
+		c.JSON(http.StatusForbidden, errorResponse(ErrForbidden))
+		return
+	}
+
 	resp, err := h.storage.User().Get(int64(id))
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+
 		c.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
@@ -83,6 +126,7 @@ func (h *handlerV1) GetUser(c *gin.Context) {
 	c.JSON(http.StatusOK, parseUserModel(resp))
 }
 
+// @Security ApiKeyAuth
 // @Router /users [get]
 // @Summary Get all users
 // @Description Get all users
@@ -126,6 +170,7 @@ func getUsersResponse(data *repo.GetAllUsersResult) *models.GetAllUsersResponse
 	return &response
 }
 
+// @Security ApiKeyAuth
 // @Router /users/{id} [put]
 // @Summary Update a users
 // @Description Update a user
@@ -153,12 +198,16 @@ func (h *handlerV1) UpdateUser(c *gin.Context) {
 		return
 	}
 
+	if !h.canAccessUser(c, id) {
+		c.JSON(http.StatusForbidden, errorResponse(ErrForbidden))
+		return
+	}
+
 	updated, err := h.storage.User().Update(&repo.User{
 		ID:          id,
 		FirstName:   req.FirstName,
 		LastName:    req.LastName,
 		PhoneNumber: req.PhoneNumber,
-		Email:       req.Email,
 		ImageURL:    req.ImageURL,
 	})
 	if err != nil {
@@ -166,9 +215,10 @@ func (h *handlerV1) UpdateUser(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, updated)
+	c.JSON(http.StatusOK, parseUserModel(updated))
 }
 
+// @Security ApiKeyAuth
 // @Router /users/{id} [delete]
 // @Summary Delete a user
 // @Description Delete a user
@@ -185,7 +235,7 @@ func (h *handlerV1) DeleteUser(c *gin.Context) {
 		return
 	}
 
-	err = h.storage.User().Delete(id)
+	err = h.purgeUser(id)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
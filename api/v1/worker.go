@@ -0,0 +1,22 @@
+package v1
+
+import (
+	"context"
+	"time"
+)
+
+// runPeriodically runs job right away and then every interval until ctx is done
+func runPeriodically(ctx context.Context, interval time.Duration, job func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		job()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package api
+
+import (
+	"context"
+
+	v1 "github.com/mirasildev/note_project/api/v1"
+)
+
+// RunWorkers runs the background jobs of the service until ctx is done
+func RunWorkers(ctx context.Context, opt *RouterOptions) {
+	handlerV1 := v1.New(&v1.HandlerV1Options{
+		Cfg:      opt.Cfg,
+		Storage:  opt.Storage,
+		InMemory: opt.InMemory,
+	})
+
+	go handlerV1.RunTrashPurge(ctx)
+	handlerV1.RunAccountPurge(ctx)
+}
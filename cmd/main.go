@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 
@@ -11,12 +12,24 @@ import (
 
 	"github.com/mirasildev/note_project/api"
 	"github.com/mirasildev/note_project/config"
+	"github.com/mirasildev/note_project/pkg/utils"
 	"github.com/mirasildev/note_project/storage"
 )
 
 func main() {
 	cfg := config.Load(".")
 	fmt.Println(cfg)
+
+	_, err := utils.LoadKeySet(&cfg)
+	if err != nil {
+		log.Fatalf("failed to load jwt keys: %v", err)
+	}
+
+	_, err = utils.LoadPasswordPolicy(&cfg)
+	if err != nil {
+		log.Fatalf("failed to load password policy: %v", err)
+	}
+
 	psqlUrl := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
 		cfg.Postgres.Host,
 		cfg.Postgres.Port,
@@ -37,11 +50,15 @@ func main() {
 	strg := storage.NewStoragePg(psqlConn)
 	inMemory := storage.NewInMemoryStorage(rdb)
 
-	apiServer := api.New(&api.RouterOptions{
+	routerOptions := &api.RouterOptions{
 		Cfg: &cfg,
 		Storage: strg,
 		InMemory: inMemory,
-	})
+	}
+
+	go api.RunWorkers(context.Background(), routerOptions)
+
+	apiServer := api.New(routerOptions)
 
 	err = apiServer.Run(cfg.HttpPort)
 	if err != nil {
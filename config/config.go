@@ -1,6 +1,9 @@
 package config
 
 import (
+	"strings"
+	"time"
+
 	"github.com/joho/godotenv"
 	"github.com/spf13/viper"
 )
@@ -11,6 +14,89 @@ type Config struct {
 	Smtp          Smtp
 	Redis         Redis
 	AuthSecretKey string
+	// MagicLinkURL is where the links of magic link emails point to
+	MagicLinkURL string
+	// AccountDeletionCancelURL is where the links to cancel an account deletion point to
+	AccountDeletionCancelURL string
+
+	// AccountDeletionGracePeriod is how long a deleted account can still be restored
+	AccountDeletionGracePeriod time.Duration
+	// AccountPurgeInterval is how often accounts past the grace period are purged
+	AccountPurgeInterval time.Duration
+
+	// NoteTrashRetention is how long deleted notes are kept in the trash
+	NoteTrashRetention time.Duration
+	// TrashPurgeInterval is how often notes past the retention are purged
+	TrashPurgeInterval time.Duration
+	// NoteRevisionLimit is how many revisions are kept per note
+	// unless the user chose another limit
+	NoteRevisionLimit int
+
+	AccessTokenDuration  time.Duration
+	RefreshTokenDuration time.Duration
+
+	Jwt Jwt
+
+	WebAuthn WebAuthn
+
+	PasswordPolicy PasswordPolicy
+	PasswordHash   PasswordHash
+
+	// OidcProviders are the external identity providers users may sign in with, by name
+	OidcProviders map[string]OidcProvider
+}
+
+type Jwt struct {
+	// SigningMethod is one of HS256, RS256 or EdDSA.
+	// HS256 signs with AuthSecretKey, the others with PrivateKeyFile.
+	SigningMethod  string
+	KeyID          string
+	PrivateKeyFile string
+	// PublicKeyFiles are the keys of previous signing keys by key id,
+	// tokens signed by them are still accepted during rotation
+	PublicKeyFiles map[string]string
+	// Issuer and Audience are put into the iss and aud claims and,
+	// when set, required from every token
+	Issuer   string
+	Audience string
+}
+
+type PasswordPolicy struct {
+	MinLength     int
+	MaxLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	// DenyListFile lists common passwords which are rejected, one per line
+	DenyListFile string
+}
+
+type PasswordHash struct {
+	// Algorithm is argon2id or bcrypt. Passwords stored with another
+	// algorithm or other parameters are rehashed on the next login.
+	Algorithm  string
+	BcryptCost int
+	// Argon2Memory is in KiB
+	Argon2Memory      uint32
+	Argon2Iterations  uint32
+	Argon2Parallelism uint8
+}
+
+type WebAuthn struct {
+	// RPID is the domain passkeys are scoped to
+	RPID   string
+	RPName string
+	// Origins are the origins of the web apps allowed to use passkeys
+	Origins []string
+}
+
+type OidcProvider struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// RedirectURL must point to /v1/auth/oidc/{name}/callback
+	RedirectURL string
 }
 
 type PostgresConfig struct {
@@ -36,6 +122,28 @@ func Load(path string) Config {
 	conf := viper.New()
 	conf.AutomaticEnv()
 
+	conf.SetDefault("ACCESS_TOKEN_DURATION", "15m")
+	conf.SetDefault("REFRESH_TOKEN_DURATION", "720h")
+	conf.SetDefault("JWT_SIGNING_METHOD", "HS256")
+	conf.SetDefault("MAGIC_LINK_URL", "http://localhost:8000/v1/auth/magic-link/callback")
+	conf.SetDefault("ACCOUNT_DELETION_CANCEL_URL", "http://localhost:8000/v1/auth/account-deletion/cancel")
+	conf.SetDefault("ACCOUNT_DELETION_GRACE_PERIOD", "720h")
+	conf.SetDefault("ACCOUNT_PURGE_INTERVAL", "1h")
+	conf.SetDefault("NOTE_TRASH_RETENTION", "720h")
+	conf.SetDefault("TRASH_PURGE_INTERVAL", "1h")
+	conf.SetDefault("NOTE_REVISION_LIMIT", 50)
+	conf.SetDefault("PASSWORD_MIN_LENGTH", 8)
+	conf.SetDefault("PASSWORD_MAX_LENGTH", 128)
+	conf.SetDefault("PASSWORD_DENY_LIST_FILE", "./config/common_passwords.txt")
+	conf.SetDefault("PASSWORD_HASH_ALGORITHM", "argon2id")
+	conf.SetDefault("BCRYPT_COST", 10)
+	conf.SetDefault("ARGON2_MEMORY", 19456)
+	conf.SetDefault("ARGON2_ITERATIONS", 2)
+	conf.SetDefault("ARGON2_PARALLELISM", 1)
+	conf.SetDefault("WEBAUTHN_RP_ID", "localhost")
+	conf.SetDefault("WEBAUTHN_RP_NAME", "Note")
+	conf.SetDefault("WEBAUTHN_ORIGINS", "http://localhost:8000")
+
 	cfg := Config{
 		HttpPort: conf.GetString("HTTP_PORT"),
 		Postgres: PostgresConfig{
@@ -53,7 +161,92 @@ func Load(path string) Config {
 			Addr: conf.GetString("REDIS_ADDR"),
 		},
 		AuthSecretKey: conf.GetString("AUTH_SECRET_KEY"),
+		MagicLinkURL:  conf.GetString("MAGIC_LINK_URL"),
+
+		AccountDeletionCancelURL:   conf.GetString("ACCOUNT_DELETION_CANCEL_URL"),
+		AccountDeletionGracePeriod: conf.GetDuration("ACCOUNT_DELETION_GRACE_PERIOD"),
+		AccountPurgeInterval:       conf.GetDuration("ACCOUNT_PURGE_INTERVAL"),
+
+		NoteTrashRetention: conf.GetDuration("NOTE_TRASH_RETENTION"),
+		TrashPurgeInterval: conf.GetDuration("TRASH_PURGE_INTERVAL"),
+		NoteRevisionLimit:  conf.GetInt("NOTE_REVISION_LIMIT"),
+
+		AccessTokenDuration:  conf.GetDuration("ACCESS_TOKEN_DURATION"),
+		RefreshTokenDuration: conf.GetDuration("REFRESH_TOKEN_DURATION"),
+
+		Jwt: Jwt{
+			SigningMethod:  conf.GetString("JWT_SIGNING_METHOD"),
+			KeyID:          conf.GetString("JWT_KEY_ID"),
+			PrivateKeyFile: conf.GetString("JWT_PRIVATE_KEY_FILE"),
+			PublicKeyFiles: parseKeyValues(conf.GetString("JWT_PUBLIC_KEY_FILES")),
+			Issuer:         conf.GetString("JWT_ISSUER"),
+			Audience:       conf.GetString("JWT_AUDIENCE"),
+		},
 	}
 
+	cfg.PasswordPolicy = PasswordPolicy{
+		MinLength:     conf.GetInt("PASSWORD_MIN_LENGTH"),
+		MaxLength:     conf.GetInt("PASSWORD_MAX_LENGTH"),
+		RequireUpper:  conf.GetBool("PASSWORD_REQUIRE_UPPER"),
+		RequireLower:  conf.GetBool("PASSWORD_REQUIRE_LOWER"),
+		RequireDigit:  conf.GetBool("PASSWORD_REQUIRE_DIGIT"),
+		RequireSymbol: conf.GetBool("PASSWORD_REQUIRE_SYMBOL"),
+		DenyListFile:  conf.GetString("PASSWORD_DENY_LIST_FILE"),
+	}
+
+	cfg.PasswordHash = PasswordHash{
+		Algorithm:         conf.GetString("PASSWORD_HASH_ALGORITHM"),
+		BcryptCost:        conf.GetInt("BCRYPT_COST"),
+		Argon2Memory:      conf.GetUint32("ARGON2_MEMORY"),
+		Argon2Iterations:  conf.GetUint32("ARGON2_ITERATIONS"),
+		Argon2Parallelism: uint8(conf.GetUint("ARGON2_PARALLELISM")),
+	}
+
+	cfg.WebAuthn = WebAuthn{
+		RPID:    conf.GetString("WEBAUTHN_RP_ID"),
+		RPName:  conf.GetString("WEBAUTHN_RP_NAME"),
+		Origins: strings.Split(conf.GetString("WEBAUTHN_ORIGINS"), ","),
+	}
+
+	cfg.OidcProviders = loadOidcProviders(conf)
+
 	return cfg
 }
+
+// loadOidcProviders reads the providers listed in OIDC_PROVIDERS,
+// every provider is configured by OIDC_{NAME}_* variables
+func loadOidcProviders(conf *viper.Viper) map[string]OidcProvider {
+	providers := make(map[string]OidcProvider)
+
+	for _, name := range strings.Split(conf.GetString("OIDC_PROVIDERS"), ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		providers[name] = OidcProvider{
+			Issuer:       conf.GetString(prefix + "ISSUER"),
+			ClientID:     conf.GetString(prefix + "CLIENT_ID"),
+			ClientSecret: conf.GetString(prefix + "CLIENT_SECRET"),
+			RedirectURL:  conf.GetString(prefix + "REDIRECT_URL"),
+		}
+	}
+
+	return providers
+}
+
+// parseKeyValues parses a list like "key1=value1,key2=value2"
+func parseKeyValues(s string) map[string]string {
+	result := make(map[string]string)
+
+	for _, pair := range strings.Split(s, ",") {
+		key, value, found := strings.Cut(strings.TrimSpace(pair), "=")
+		if !found {
+			continue
+		}
+		result[key] = value
+	}
+
+	return result
+}
@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS audit_logs(
+        id BIGSERIAL PRIMARY KEY,
+        -- user_id is kept after the user is deleted, so there is no foreign key
+        user_id INTEGER,
+        email VARCHAR,
+        event VARCHAR(50) NOT NULL,
+        ip_address VARCHAR NOT NULL,
+        user_agent TEXT NOT NULL,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS audit_logs_user_id_idx ON audit_logs(user_id, created_at);
+CREATE INDEX IF NOT EXISTS audit_logs_event_idx ON audit_logs(event, created_at);
+
+CREATE OR REPLACE FUNCTION audit_logs_append_only() RETURNS TRIGGER AS $$
+BEGIN
+        RAISE EXCEPTION 'audit_logs is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_logs_append_only ON audit_logs;
+CREATE TRIGGER audit_logs_append_only
+        BEFORE UPDATE OR DELETE ON audit_logs
+        FOR EACH ROW EXECUTE FUNCTION audit_logs_append_only();
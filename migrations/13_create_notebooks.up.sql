@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS notebooks(
+        id SERIAL PRIMARY KEY,
+        user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+        -- deleting a notebook deletes the notebooks inside of it
+        parent_id INTEGER REFERENCES notebooks(id) ON DELETE CASCADE,
+        name VARCHAR(100) NOT NULL,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        updated_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS notebooks_user_id_idx ON notebooks(user_id);
+CREATE INDEX IF NOT EXISTS notebooks_parent_id_idx ON notebooks(parent_id);
+
+-- the notes of a deleted notebook are kept without a notebook
+ALTER TABLE notes ADD COLUMN IF NOT EXISTS notebook_id INTEGER REFERENCES notebooks(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS notes_notebook_id_idx ON notes(notebook_id);
@@ -0,0 +1,3 @@
+ALTER TABLE users DROP COLUMN IF EXISTS note_revision_limit;
+
+DROP TABLE IF EXISTS note_revisions;
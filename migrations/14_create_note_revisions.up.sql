@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS note_revisions(
+        id SERIAL PRIMARY KEY,
+        note_id INTEGER NOT NULL REFERENCES notes(id) ON DELETE CASCADE,
+        number INTEGER NOT NULL,
+        title VARCHAR NOT NULL,
+        description TEXT NOT NULL,
+        author_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        UNIQUE(note_id, number)
+);
+
+-- the current content of the existing notes is their first revision
+INSERT INTO note_revisions(note_id, number, title, description, author_id, created_at)
+SELECT id, 1, title, description, user_id, coalesce(updated_at, created_at) FROM notes
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS note_revision_limit INTEGER;
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS passkeys(
+        id VARCHAR PRIMARY KEY,
+        user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+        name VARCHAR(100) NOT NULL,
+        public_key BYTEA NOT NULL,
+        sign_count BIGINT NOT NULL DEFAULT 0,
+        last_used_at TIMESTAMP,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS passkeys_user_id_idx ON passkeys(user_id);
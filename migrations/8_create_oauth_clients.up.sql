@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS oauth_clients(
+        id VARCHAR PRIMARY KEY,
+        user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+        name VARCHAR(100) NOT NULL,
+        secret_hash VARCHAR,
+        redirect_uris TEXT[] NOT NULL,
+        scopes TEXT[] NOT NULL,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS oauth_clients_user_id_idx ON oauth_clients(user_id);
+
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS client_id VARCHAR;
+
+CREATE INDEX IF NOT EXISTS sessions_client_id_idx ON sessions(client_id);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS files;
+
+ALTER TABLE users DROP COLUMN IF EXISTS deletion_scheduled_at;
@@ -0,0 +1,163 @@
+// Package diff compares texts line by line
+package diff
+
+import "strings"
+
+type Op string
+
+const (
+	OpEqual  Op = "equal"
+	OpInsert Op = "insert"
+	OpDelete Op = "delete"
+)
+
+const (
+	// MaxLines is the number of differing lines of both texts together
+	// above which the texts are not searched for common lines
+	MaxLines = 10000
+	// MaxEdits is the length of the edit script after which the search
+	// gives up. The memory of the search grows with its square.
+	MaxEdits = 1000
+)
+
+// Line is a line of the old text, the new text or both of them
+type Line struct {
+	Op   Op
+	Text string
+}
+
+// Lines returns the shortest edit script turning a into b,
+// found with the Myers algorithm. When the texts differ too much the
+// differing lines are returned as deleted and inserted as a whole.
+func Lines(a, b string) []Line {
+	x, y := splitLines(a), splitLines(b)
+
+	// common lines at the start and the end don't take part in the search
+	prefix := 0
+	for prefix < len(x) && prefix < len(y) && x[prefix] == y[prefix] {
+		prefix++
+	}
+
+	suffix := 0
+	for suffix < len(x)-prefix && suffix < len(y)-prefix &&
+		x[len(x)-1-suffix] == y[len(y)-1-suffix] {
+		suffix++
+	}
+
+	result := make([]Line, 0, len(x)+len(y)-prefix-suffix)
+	result = appendLines(result, OpEqual, x[:prefix])
+
+	middleX, middleY := x[prefix:len(x)-suffix], y[prefix:len(y)-suffix]
+
+	edits, ok := shortestEdits(middleX, middleY)
+	if !ok {
+		edits = appendLines(appendLines(nil, OpDelete, middleX), OpInsert, middleY)
+	}
+	result = append(result, edits...)
+
+	return appendLines(result, OpEqual, x[len(x)-suffix:])
+}
+
+// shortestEdits runs the Myers search. It reports false
+// if the texts are larger or differ more than the limits.
+func shortestEdits(x, y []string) ([]Line, bool) {
+	n, m := len(x), len(y)
+
+	if n+m > MaxLines {
+		return nil, false
+	}
+
+	// v[offset+k] is the furthest x reached on diagonal k, trace keeps
+	// the diagonals -d..d of v as they were before round d
+	offset := n + m
+	v := make([]int, 2*offset+2)
+	trace := make([][]int, 0)
+
+search:
+	for d := 0; d <= n+m; d++ {
+		if d > MaxEdits {
+			return nil, false
+		}
+
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+
+		for k := -d; k <= d; k += 2 {
+			var i int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				i = v[offset+k+1]
+			} else {
+				i = v[offset+k-1] + 1
+			}
+
+			j := i - k
+			for i < n && j < m && x[i] == y[j] {
+				i++
+				j++
+			}
+
+			v[offset+k] = i
+			if i >= n && j >= m {
+				break search
+			}
+		}
+	}
+
+	result := make([]Line, 0, n+m)
+	i, j := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		// diagonal k of round d is at index d+k of its snapshot
+		v := trace[d]
+		k := i - j
+
+		var prevK int
+		if k == -d || (k != d && v[d+k-1] < v[d+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+
+		prevI := 0
+		if d > 0 {
+			prevI = v[d+prevK]
+		}
+		prevJ := prevI - prevK
+
+		for i > prevI && j > prevJ {
+			i--
+			j--
+			result = append(result, Line{Op: OpEqual, Text: x[i]})
+		}
+
+		if d > 0 {
+			if i == prevI {
+				j--
+				result = append(result, Line{Op: OpInsert, Text: y[j]})
+			} else {
+				i--
+				result = append(result, Line{Op: OpDelete, Text: x[i]})
+			}
+		}
+	}
+
+	for l, r := 0, len(result)-1; l < r; l, r = l+1, r-1 {
+		result[l], result[r] = result[r], result[l]
+	}
+
+	return result, true
+}
+
+func appendLines(lines []Line, op Op, texts []string) []Line {
+	for _, text := range texts {
+		lines = append(lines, Line{Op: op, Text: text})
+	}
+
+	return lines
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
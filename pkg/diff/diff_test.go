@@ -0,0 +1,131 @@
+package diff
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// apply rebuilds both texts from the edit script
+func apply(lines []Line) (string, string) {
+	var a, b []string
+	for _, l := range lines {
+		if l.Op != OpInsert {
+			a = append(a, l.Text)
+		}
+		if l.Op != OpDelete {
+			b = append(b, l.Text)
+		}
+	}
+
+	return strings.Join(a, "\n"), strings.Join(b, "\n")
+}
+
+func TestLines(t *testing.T) {
+	lines := Lines("a\nb\nc\nd", "a\nc\nd\ne")
+	require.Equal(t, []Line{
+		{Op: OpEqual, Text: "a"},
+		{Op: OpDelete, Text: "b"},
+		{Op: OpEqual, Text: "c"},
+		{Op: OpEqual, Text: "d"},
+		{Op: OpInsert, Text: "e"},
+	}, lines)
+}
+
+func TestLinesEdgeCases(t *testing.T) {
+	require.Empty(t, Lines("", ""))
+
+	require.Equal(t, []Line{{Op: OpInsert, Text: "a"}}, Lines("", "a"))
+	require.Equal(t, []Line{{Op: OpDelete, Text: "a"}}, Lines("a", ""))
+	require.Equal(t, []Line{{Op: OpEqual, Text: "a"}}, Lines("a\n", "a"))
+
+	cases := [][2]string{
+		{"a\nb\nc\na\nb\nb\na", "c\nb\na\nb\na\nc"},
+		{"one\ntwo\nthree", "zero\none\nthree\nfour"},
+		{"x\ny", "y\nx"},
+	}
+	for _, c := range cases {
+		a, b := apply(Lines(c[0], c[1]))
+		require.Equal(t, c[0], a)
+		require.Equal(t, c[1], b)
+	}
+
+	// the classic example of the Myers paper needs 5 edits
+	edits := 0
+	for _, l := range Lines("a\nb\nc\na\nb\nb\na", "c\nb\na\nb\na\nc") {
+		if l.Op != OpEqual {
+			edits++
+		}
+	}
+	require.Equal(t, 5, edits)
+}
+
+func numberedLines(prefix string, count int) string {
+	lines := make([]string, count)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("%s line %d", prefix, i)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func TestLinesLargeInput(t *testing.T) {
+	a, b := numberedLines("old", 4000), numberedLines("new", 4000)
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	lines := Lines(a, b)
+	runtime.ReadMemStats(&after)
+
+	require.Less(t, after.TotalAlloc-before.TotalAlloc, uint64(64<<20))
+
+	gotA, gotB := apply(lines)
+	require.Equal(t, a, gotA)
+	require.Equal(t, b, gotB)
+	require.Equal(t, OpDelete, lines[0].Op)
+	require.Equal(t, OpInsert, lines[len(lines)-1].Op)
+}
+
+func TestLinesLargeInputWithFewChanges(t *testing.T) {
+	a := numberedLines("same", 20000)
+	b := strings.Replace(a, "same line 10000\n", "changed line\n", 1)
+
+	lines := Lines(a, b)
+
+	gotA, gotB := apply(lines)
+	require.Equal(t, a, gotA)
+	require.Equal(t, b, gotB)
+
+	edits := 0
+	for _, l := range lines {
+		if l.Op != OpEqual {
+			edits++
+		}
+	}
+	require.Equal(t, 2, edits)
+}
+
+func TestLinesManyChanges(t *testing.T) {
+	a := strings.Split(numberedLines("same", 600), "\n")
+	b := append([]string(nil), a...)
+	for i := 0; i < len(b); i += 3 {
+		b[i] = fmt.Sprintf("changed line %d", i)
+	}
+
+	lines := Lines(strings.Join(a, "\n"), strings.Join(b, "\n"))
+
+	gotA, gotB := apply(lines)
+	require.Equal(t, strings.Join(a, "\n"), gotA)
+	require.Equal(t, strings.Join(b, "\n"), gotB)
+
+	edits := 0
+	for _, l := range lines {
+		if l.Op != OpEqual {
+			edits++
+		}
+	}
+	require.Equal(t, 400, edits)
+}
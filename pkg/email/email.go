@@ -17,8 +17,11 @@ type SendEmailRequest struct {
 }
 
 const (
-	VerificationEmail   = "varification_email"
-	ForgotPasswordEmail = "forgot_password_email"
+	VerificationEmail    = "varification_email"
+	ForgotPasswordEmail  = "forgot_password_email"
+	AccountLockedEmail   = "account_locked_email"
+	MagicLinkEmail       = "magic_link_email"
+	AccountDeletionEmail = "account_deletion_email"
 )
 
 func SendEmail(cfg *config.Config, req *SendEmailRequest) error {
@@ -56,6 +59,12 @@ func getTemplatePath(emailType string) string {
 		return "./templates/verification_email.html"
 	case ForgotPasswordEmail:
 		return "./templates/forgot_password_email.html"
+	case AccountLockedEmail:
+		return "./templates/account_locked_email.html"
+	case MagicLinkEmail:
+		return "./templates/magic_link_email.html"
+	case AccountDeletionEmail:
+		return "./templates/account_deletion_email.html"
 	}
 
 	return ""
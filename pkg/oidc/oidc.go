@@ -0,0 +1,158 @@
+package oidc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"sync"
+	"time"
+
+	coreoidc "github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+
+	"github.com/mirasildev/note_project/config"
+)
+
+var (
+	ErrUnknownProvider = errors.New("unknown identity provider")
+	ErrMissingIDToken  = errors.New("identity provider returned no id token")
+	ErrInvalidNonce    = errors.New("id token nonce does not match")
+)
+
+// Identity is the account of the user at the identity provider
+type Identity struct {
+	Subject       string `json:"sub"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	GivenName     string `json:"given_name"`
+	FamilyName    string `json:"family_name"`
+}
+
+// Provider runs the authorization code flow with PKCE against one identity provider
+type Provider struct {
+	oauth2   oauth2.Config
+	verifier *coreoidc.IDTokenVerifier
+}
+
+// NewProvider reads the discovery document of the issuer
+func NewProvider(ctx context.Context, cfg config.OidcProvider) (*Provider, error) {
+	provider, err := coreoidc.NewProvider(ctx, cfg.Issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Provider{
+		oauth2: oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			RedirectURL:  cfg.RedirectURL,
+			Endpoint:     provider.Endpoint(),
+			Scopes:       []string{coreoidc.ScopeOpenID, "email", "profile"},
+		},
+		verifier: provider.Verifier(&coreoidc.Config{
+			ClientID: cfg.ClientID,
+		}),
+	}, nil
+}
+
+// AuthCodeURL returns the url the user is redirected to for signing in
+func (p *Provider) AuthCodeURL(state, nonce, codeVerifier string) string {
+	return p.oauth2.AuthCodeURL(
+		state,
+		coreoidc.Nonce(nonce),
+		oauth2.SetAuthURLParam("code_challenge", CodeChallenge(codeVerifier)),
+		oauth2.SetAuthURLParam("code_challenge_method", "S256"),
+	)
+}
+
+// Exchange trades the code from the callback for an id token and returns the identity in it
+func (p *Provider) Exchange(ctx context.Context, code, codeVerifier, nonce string) (*Identity, error) {
+	token, err := p.oauth2.Exchange(ctx, code, oauth2.SetAuthURLParam("code_verifier", codeVerifier))
+	if err != nil {
+		return nil, err
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, ErrMissingIDToken
+	}
+
+	idToken, err := p.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if idToken.Nonce != nonce {
+		return nil, ErrInvalidNonce
+	}
+
+	var identity Identity
+	err = idToken.Claims(&identity)
+	if err != nil {
+		return nil, err
+	}
+
+	return &identity, nil
+}
+
+// CodeChallenge returns the S256 PKCE challenge of the verifier
+func CodeChallenge(codeVerifier string) string {
+	sum := sha256.Sum256([]byte(codeVerifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// discoveryTimeout limits how long a request waits for the discovery document
+const discoveryTimeout = 10 * time.Second
+
+// Providers discovers the configured providers on first use
+// and keeps them for the next requests
+type Providers struct {
+	cfg       map[string]config.OidcProvider
+	mu        sync.Mutex
+	providers map[string]*Provider
+}
+
+func NewProviders(cfg map[string]config.OidcProvider) *Providers {
+	return &Providers{
+		cfg:       cfg,
+		providers: make(map[string]*Provider),
+	}
+}
+
+// Get returns the discovered provider. Discovery runs without holding the lock,
+// so a slow identity provider doesn't block sign ins with the others.
+// Concurrent first requests may discover the same provider more than once,
+// the first result is kept.
+func (ps *Providers) Get(ctx context.Context, name string) (*Provider, error) {
+	ps.mu.Lock()
+	provider, ok := ps.providers[name]
+	ps.mu.Unlock()
+
+	if ok {
+		return provider, nil
+	}
+
+	cfg, ok := ps.cfg[name]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, discoveryTimeout)
+	defer cancel()
+
+	provider, err := NewProvider(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if discovered, ok := ps.providers[name]; ok {
+		return discovered, nil
+	}
+
+	ps.providers[name] = provider
+	return provider, nil
+}
@@ -0,0 +1,293 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mirasildev/note_project/config"
+)
+
+const (
+	mockClientID     = "note-client"
+	mockClientSecret = "note-secret"
+	mockRedirectURL  = "http://localhost:8000/v1/auth/oidc/mock/callback"
+	mockKeyID        = "mock-key"
+)
+
+type mockAuthorization struct {
+	challenge string
+	nonce     string
+}
+
+// mockProvider is a minimal OpenID provider which supports discovery,
+// the authorization code flow with S256 PKCE and RS256 id tokens
+type mockProvider struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	claims jwt.MapClaims
+
+	mu    sync.Mutex
+	codes map[string]mockAuthorization
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	m := &mockProvider{
+		key:   key,
+		codes: make(map[string]mockAuthorization),
+		claims: jwt.MapClaims{
+			"sub":            "mock-user-1",
+			"email":          "john@example.com",
+			"email_verified": true,
+			"given_name":     "John",
+			"family_name":    "Doe",
+		},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", m.discovery)
+	mux.HandleFunc("/keys", m.keys)
+	mux.HandleFunc("/authorize", m.authorize)
+	mux.HandleFunc("/token", m.token)
+
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+
+	return m
+}
+
+func (m *mockProvider) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                m.server.URL,
+		"authorization_endpoint":                m.server.URL + "/authorize",
+		"token_endpoint":                        m.server.URL + "/token",
+		"jwks_uri":                              m.server.URL + "/keys",
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (m *mockProvider) keys(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": mockKeyID,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(m.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(m.key.E)).Bytes()),
+		}},
+	})
+}
+
+// authorize signs the user in right away and redirects back with a code
+func (m *mockProvider) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("client_id") != mockClientID || query.Get("code_challenge_method") != "S256" {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	code := base64.RawURLEncoding.EncodeToString(big.NewInt(time.Now().UnixNano()).Bytes())
+
+	m.mu.Lock()
+	m.codes[code] = mockAuthorization{
+		challenge: query.Get("code_challenge"),
+		nonce:     query.Get("nonce"),
+	}
+	m.mu.Unlock()
+
+	redirectURL, _ := url.Parse(query.Get("redirect_uri"))
+	redirectURL.RawQuery = url.Values{
+		"code":  {code},
+		"state": {query.Get("state")},
+	}.Encode()
+
+	http.Redirect(w, r, redirectURL.String(), http.StatusFound)
+}
+
+func (m *mockProvider) token(w http.ResponseWriter, r *http.Request) {
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok || clientID != mockClientID || clientSecret != mockClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	m.mu.Lock()
+	authorization, ok := m.codes[r.PostFormValue("code")]
+	delete(m.codes, r.PostFormValue("code"))
+	m.mu.Unlock()
+
+	if !ok || CodeChallenge(r.PostFormValue("code_verifier")) != authorization.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	claims := jwt.MapClaims{
+		"iss":   m.server.URL,
+		"aud":   mockClientID,
+		"iat":   time.Now().Unix(),
+		"exp":   time.Now().Add(time.Minute).Unix(),
+		"nonce": authorization.nonce,
+	}
+	for k, v := range m.claims {
+		claims[k] = v
+	}
+
+	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	idToken.Header["kid"] = mockKeyID
+
+	signed, err := idToken.SignedString(m.key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": "mock-access-token",
+		"token_type":   "Bearer",
+		"expires_in":   60,
+		"id_token":     signed,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func newTestProvider(t *testing.T, m *mockProvider) *Provider {
+	providers := NewProviders(map[string]config.OidcProvider{
+		"mock": {
+			Issuer:       m.server.URL,
+			ClientID:     mockClientID,
+			ClientSecret: mockClientSecret,
+			RedirectURL:  mockRedirectURL,
+		},
+	})
+
+	provider, err := providers.Get(context.Background(), "mock")
+	require.NoError(t, err)
+
+	return provider
+}
+
+// signIn follows the auth code url like a browser would and returns the code
+func signIn(t *testing.T, provider *Provider, state, nonce, codeVerifier string) string {
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	resp, err := client.Get(provider.AuthCodeURL(state, nonce, codeVerifier))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	require.Equal(t, state, location.Query().Get("state"))
+
+	return location.Query().Get("code")
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	m := newMockProvider(t)
+	provider := newTestProvider(t, m)
+
+	code := signIn(t, provider, "state-1", "nonce-1", "verifier-verifier-verifier-verifier-1")
+
+	identity, err := provider.Exchange(context.Background(), code, "verifier-verifier-verifier-verifier-1", "nonce-1")
+	require.NoError(t, err)
+	require.Equal(t, "mock-user-1", identity.Subject)
+	require.Equal(t, "john@example.com", identity.Email)
+	require.True(t, identity.EmailVerified)
+	require.Equal(t, "John", identity.GivenName)
+}
+
+func TestExchangeWithWrongCodeVerifier(t *testing.T) {
+	m := newMockProvider(t)
+	provider := newTestProvider(t, m)
+
+	code := signIn(t, provider, "state-1", "nonce-1", "verifier-verifier-verifier-verifier-1")
+
+	_, err := provider.Exchange(context.Background(), code, "verifier-verifier-verifier-verifier-2", "nonce-1")
+	require.Error(t, err)
+}
+
+func TestExchangeWithWrongNonce(t *testing.T) {
+	m := newMockProvider(t)
+	provider := newTestProvider(t, m)
+
+	code := signIn(t, provider, "state-1", "nonce-1", "verifier-verifier-verifier-verifier-1")
+
+	_, err := provider.Exchange(context.Background(), code, "verifier-verifier-verifier-verifier-1", "nonce-2")
+	require.ErrorIs(t, err, ErrInvalidNonce)
+}
+
+func TestUnknownProvider(t *testing.T) {
+	providers := NewProviders(map[string]config.OidcProvider{})
+
+	_, err := providers.Get(context.Background(), "mock")
+	require.ErrorIs(t, err, ErrUnknownProvider)
+}
+
+func TestSlowProviderDoesNotBlockOthers(t *testing.T) {
+	m := newMockProvider(t)
+
+	requested := make(chan struct{}, 1)
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested <- struct{}{}
+		<-release
+	}))
+	t.Cleanup(slow.Close)
+	t.Cleanup(func() { close(release) })
+
+	providers := NewProviders(map[string]config.OidcProvider{
+		"mock": {
+			Issuer:   m.server.URL,
+			ClientID: mockClientID,
+		},
+		"slow": {
+			Issuer:   slow.URL,
+			ClientID: mockClientID,
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go providers.Get(ctx, "slow")
+	<-requested
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := providers.Get(context.Background(), "mock")
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("discovery of a slow provider blocked the others")
+	}
+}
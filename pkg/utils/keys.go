@@ -0,0 +1,265 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sync"
+
+	"github.com/golang-jwt/jwt"
+
+	"github.com/mirasildev/note_project/config"
+)
+
+const (
+	SigningMethodHS256 = "HS256"
+	SigningMethodRS256 = "RS256"
+	SigningMethodEdDSA = "EdDSA"
+)
+
+var (
+	ErrUnknownSigningMethod = errors.New("unknown jwt signing method")
+	ErrKeyIDRequired        = errors.New("jwt key id is required for asymmetric signing")
+	ErrUnsupportedKey       = errors.New("unsupported key type")
+)
+
+// KeySet holds the key tokens are signed with and every key tokens are verified with
+type KeySet struct {
+	method     jwt.SigningMethod
+	keyID      string
+	signingKey interface{}
+	// publicKeys are the verification keys by key id, empty for HS256
+	publicKeys map[string]crypto.PublicKey
+}
+
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA keys
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519 keys
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+var keySets sync.Map
+
+// LoadKeySet reads the signing keys named in the config. The result is cached
+// per config, so calling it on startup makes token creation fail fast.
+func LoadKeySet(cfg *config.Config) (*KeySet, error) {
+	if ks, ok := keySets.Load(cfg); ok {
+		return ks.(*KeySet), nil
+	}
+
+	ks, err := newKeySet(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	keySets.Store(cfg, ks)
+	return ks, nil
+}
+
+func newKeySet(cfg *config.Config) (*KeySet, error) {
+	ks := &KeySet{
+		keyID:      cfg.Jwt.KeyID,
+		publicKeys: make(map[string]crypto.PublicKey),
+	}
+
+	switch cfg.Jwt.SigningMethod {
+	case "", SigningMethodHS256:
+		ks.method = jwt.SigningMethodHS256
+		ks.signingKey = []byte(cfg.AuthSecretKey)
+		return ks, nil
+	case SigningMethodRS256:
+		ks.method = jwt.SigningMethodRS256
+	case SigningMethodEdDSA:
+		ks.method = jwt.SigningMethodEdDSA
+	default:
+		return nil, ErrUnknownSigningMethod
+	}
+
+	if ks.keyID == "" {
+		return nil, ErrKeyIDRequired
+	}
+
+	data, err := os.ReadFile(cfg.Jwt.PrivateKeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	privateKey, err := parsePrivateKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", cfg.Jwt.PrivateKeyFile, err)
+	}
+
+	method, err := signingMethodFor(privateKey.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	if method != ks.method {
+		return nil, fmt.Errorf("private key does not match the %s signing method", ks.method.Alg())
+	}
+
+	ks.signingKey = privateKey
+	ks.publicKeys[ks.keyID] = privateKey.Public()
+
+	for keyID, path := range cfg.Jwt.PublicKeyFiles {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		key, err := parsePublicKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+
+		ks.publicKeys[keyID] = key
+	}
+
+	return ks, nil
+}
+
+type privateKey interface {
+	Public() crypto.PublicKey
+}
+
+func parsePrivateKey(data []byte) (privateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, jwt.ErrKeyMustBePEMEncoded
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return k, nil
+	case ed25519.PrivateKey:
+		return k, nil
+	}
+
+	return nil, ErrUnsupportedKey
+}
+
+func parsePublicKey(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, jwt.ErrKeyMustBePEMEncoded
+	}
+
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := signingMethodFor(key); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+func signingMethodFor(key crypto.PublicKey) (jwt.SigningMethod, error) {
+	switch key.(type) {
+	case *rsa.PublicKey:
+		return jwt.SigningMethodRS256, nil
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, nil
+	}
+
+	return nil, ErrUnsupportedKey
+}
+
+func (ks *KeySet) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(ks.method, claims)
+	if ks.keyID != "" && ks.method != jwt.SigningMethodHS256 {
+		token.Header["kid"] = ks.keyID
+	}
+
+	return token.SignedString(ks.signingKey)
+}
+
+// keyFunc picks the verification key by the kid header and makes sure
+// the token is signed with the algorithm that belongs to the key
+func (ks *KeySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	if ks.method == jwt.SigningMethodHS256 {
+		_, ok := token.Method.(*jwt.SigningMethodHMAC)
+		if !ok {
+			return nil, ErrInvalidToken
+		}
+		return ks.signingKey, nil
+	}
+
+	keyID, _ := token.Header["kid"].(string)
+	key, ok := ks.publicKeys[keyID]
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	method, err := signingMethodFor(key)
+	if err != nil || method.Alg() != token.Method.Alg() {
+		return nil, ErrInvalidToken
+	}
+
+	return key, nil
+}
+
+// JWKS returns the public verification keys in the JSON Web Key Set format
+func (ks *KeySet) JWKS() *JWKSet {
+	set := &JWKSet{
+		Keys: make([]JWK, 0, len(ks.publicKeys)),
+	}
+
+	for keyID, key := range ks.publicKeys {
+		switch k := key.(type) {
+		case *rsa.PublicKey:
+			set.Keys = append(set.Keys, JWK{
+				Kty: "RSA",
+				Kid: keyID,
+				Use: "sig",
+				Alg: SigningMethodRS256,
+				N:   base64.RawURLEncoding.EncodeToString(k.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			set.Keys = append(set.Keys, JWK{
+				Kty: "OKP",
+				Kid: keyID,
+				Use: "sig",
+				Alg: SigningMethodEdDSA,
+				Crv: "Ed25519",
+				X:   base64.RawURLEncoding.EncodeToString(k),
+			})
+		}
+	}
+
+	return set
+}
@@ -1,22 +1,165 @@
 package utils
 
 import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"strings"
 
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/bcrypt"
+
+	"github.com/mirasildev/note_project/config"
+)
+
+const (
+	PasswordHashArgon2id = "argon2id"
+	PasswordHashBcrypt   = "bcrypt"
+)
+
+const (
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
 )
 
-// HashPassword returns the bcrypt hash of the password
-func HashPassword(password string) (string, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+var (
+	ErrPasswordMismatch         = errors.New("password does not match")
+	ErrUnknownPasswordHash      = errors.New("unknown password hash format")
+	ErrUnknownPasswordAlgorithm = errors.New("unknown password hash algorithm")
+)
+
+// argon2Params are the parameters of an argon2id hash in the
+// encoded format $argon2id$v=19$m=19456,t=2,p=1$salt$hash
+type argon2Params struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+}
+
+// HashPassword returns the hash of the password with the configured algorithm
+func HashPassword(cfg *config.Config, password string) (string, error) {
+	var (
+		hashedPassword string
+		err            error
+	)
+
+	switch cfg.PasswordHash.Algorithm {
+	case PasswordHashArgon2id:
+		hashedPassword, err = hashArgon2id(password, argon2Params{
+			memory:      cfg.PasswordHash.Argon2Memory,
+			iterations:  cfg.PasswordHash.Argon2Iterations,
+			parallelism: cfg.PasswordHash.Argon2Parallelism,
+		})
+	case PasswordHashBcrypt:
+		var hash []byte
+		hash, err = bcrypt.GenerateFromPassword([]byte(password), cfg.PasswordHash.BcryptCost)
+		hashedPassword = string(hash)
+	default:
+		err = ErrUnknownPasswordAlgorithm
+	}
 	if err != nil {
 		return "", fmt.Errorf("failed to hash password: %w", err)
 	}
 
-	return string(hashedPassword), nil
+	return hashedPassword, nil
+}
+
+// CheckPassword checks if the provided password is correct or not. If it is
+// but the hash is of another algorithm or parameters than the configured ones,
+// the password is hashed again and the new hash is returned to be stored.
+func CheckPassword(cfg *config.Config, password, hashedPassword string) (string, error) {
+	var upToDate bool
+
+	if strings.HasPrefix(hashedPassword, "$"+PasswordHashArgon2id+"$") {
+		params, salt, key, err := decodeArgon2id(hashedPassword)
+		if err != nil {
+			return "", err
+		}
+
+		computed := argon2.IDKey([]byte(password), salt, params.iterations, params.memory, params.parallelism, uint32(len(key)))
+		if subtle.ConstantTimeCompare(computed, key) != 1 {
+			return "", ErrPasswordMismatch
+		}
+
+		upToDate = cfg.PasswordHash.Algorithm == PasswordHashArgon2id &&
+			params.memory == cfg.PasswordHash.Argon2Memory &&
+			params.iterations == cfg.PasswordHash.Argon2Iterations &&
+			params.parallelism == cfg.PasswordHash.Argon2Parallelism
+	} else {
+		err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return "", ErrPasswordMismatch
+		}
+		if err != nil {
+			return "", err
+		}
+
+		cost, err := bcrypt.Cost([]byte(hashedPassword))
+		if err != nil {
+			return "", err
+		}
+
+		upToDate = cfg.PasswordHash.Algorithm == PasswordHashBcrypt && cost == cfg.PasswordHash.BcryptCost
+	}
+
+	if upToDate {
+		return "", nil
+	}
+
+	return HashPassword(cfg, password)
+}
+
+func hashArgon2id(password string, params argon2Params) (string, error) {
+	salt := make([]byte, argon2SaltLength)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, params.iterations, params.memory, params.parallelism, argon2KeyLength)
+
+	return fmt.Sprintf(
+		"$%s$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		PasswordHashArgon2id,
+		argon2.Version,
+		params.memory,
+		params.iterations,
+		params.parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
 }
 
-// CheckPassword checks if the provided password is correct or not
-func CheckPassword(password, hashedPassword string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+func decodeArgon2id(hashedPassword string) (*argon2Params, []byte, []byte, error) {
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, key
+	parts := strings.Split(hashedPassword, "$")
+	if len(parts) != 6 {
+		return nil, nil, nil, ErrUnknownPasswordHash
+	}
+
+	var version int
+	_, err := fmt.Sscanf(parts[2], "v=%d", &version)
+	if err != nil || version != argon2.Version {
+		return nil, nil, nil, ErrUnknownPasswordHash
+	}
+
+	var params argon2Params
+	_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.iterations, &params.parallelism)
+	if err != nil {
+		return nil, nil, nil, ErrUnknownPasswordHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return nil, nil, nil, ErrUnknownPasswordHash
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return nil, nil, nil, ErrUnknownPasswordHash
+	}
+
+	return &params, salt, key, nil
 }
@@ -0,0 +1,130 @@
+package utils
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mirasildev/note_project/config"
+)
+
+// bcryptMaxLength is the number of bytes bcrypt uses, the rest is ignored
+const bcryptMaxLength = 72
+
+// ErrWeakPassword is wrapped by every password policy violation
+var ErrWeakPassword = errors.New("password is too weak")
+
+// PasswordPolicy decides which passwords users may choose
+type PasswordPolicy struct {
+	cfg       config.PasswordPolicy
+	algorithm string
+	denyList  map[string]struct{}
+}
+
+var passwordPolicies sync.Map
+
+// LoadPasswordPolicy reads the deny list named in the config. The result is
+// cached per config, so calling it on startup makes validation fail fast.
+func LoadPasswordPolicy(cfg *config.Config) (*PasswordPolicy, error) {
+	if p, ok := passwordPolicies.Load(cfg); ok {
+		return p.(*PasswordPolicy), nil
+	}
+
+	p := &PasswordPolicy{
+		cfg:       cfg.PasswordPolicy,
+		algorithm: cfg.PasswordHash.Algorithm,
+		denyList:  make(map[string]struct{}),
+	}
+
+	if cfg.PasswordPolicy.DenyListFile != "" {
+		err := p.loadDenyList(cfg.PasswordPolicy.DenyListFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	passwordPolicies.Store(cfg, p)
+	return p, nil
+}
+
+func (p *PasswordPolicy) loadDenyList(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open password deny list: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		password := strings.TrimSpace(scanner.Text())
+		if password == "" || strings.HasPrefix(password, "#") {
+			continue
+		}
+		p.denyList[strings.ToLower(password)] = struct{}{}
+	}
+
+	return scanner.Err()
+}
+
+// ValidatePassword checks the password against the configured policy
+func ValidatePassword(cfg *config.Config, password string) error {
+	p, err := LoadPasswordPolicy(cfg)
+	if err != nil {
+		return err
+	}
+
+	return p.Validate(password)
+}
+
+// Validate returns an error wrapping ErrWeakPassword if the password breaks the policy
+func (p *PasswordPolicy) Validate(password string) error {
+	length := utf8.RuneCountInString(password)
+	if length < p.cfg.MinLength {
+		return fmt.Errorf("%w: it must be at least %d characters long", ErrWeakPassword, p.cfg.MinLength)
+	}
+
+	if (p.cfg.MaxLength > 0 && length > p.cfg.MaxLength) ||
+		(p.algorithm == PasswordHashBcrypt && len(password) > bcryptMaxLength) {
+		return fmt.Errorf("%w: it is too long", ErrWeakPassword)
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			hasSymbol = true
+		}
+	}
+
+	for _, rule := range []struct {
+		required bool
+		ok       bool
+		name     string
+	}{
+		{p.cfg.RequireUpper, hasUpper, "an uppercase letter"},
+		{p.cfg.RequireLower, hasLower, "a lowercase letter"},
+		{p.cfg.RequireDigit, hasDigit, "a digit"},
+		{p.cfg.RequireSymbol, hasSymbol, "a symbol"},
+	} {
+		if rule.required && !rule.ok {
+			return fmt.Errorf("%w: it must contain %s", ErrWeakPassword, rule.name)
+		}
+	}
+
+	if _, denied := p.denyList[strings.ToLower(password)]; denied {
+		return fmt.Errorf("%w: it is too common", ErrWeakPassword)
+	}
+
+	return nil
+}
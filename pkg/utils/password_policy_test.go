@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mirasildev/note_project/config"
+)
+
+func TestPasswordPolicy(t *testing.T) {
+	denyList := filepath.Join(t.TempDir(), "common_passwords.txt")
+	err := os.WriteFile(denyList, []byte("# common\npassword1\n\nqwerty123\n"), 0600)
+	require.NoError(t, err)
+
+	cfg := &config.Config{
+		PasswordPolicy: config.PasswordPolicy{
+			MinLength:    8,
+			MaxLength:    20,
+			RequireLower: true,
+			RequireDigit: true,
+			DenyListFile: denyList,
+		},
+		PasswordHash: config.PasswordHash{Algorithm: PasswordHashArgon2id},
+	}
+
+	require.NoError(t, ValidatePassword(cfg, "correct horse 1"))
+
+	for _, password := range []string{
+		"short1",
+		"much too long password 1",
+		"NO LOWERCASE 1",
+		"no digits at all",
+		"Password1",
+		"QWERTY123",
+	} {
+		require.ErrorIs(t, ValidatePassword(cfg, password), ErrWeakPassword, password)
+	}
+}
+
+func TestPasswordPolicyBcryptLength(t *testing.T) {
+	cfg := &config.Config{
+		PasswordPolicy: config.PasswordPolicy{MinLength: 8, MaxLength: 128},
+		PasswordHash:   config.PasswordHash{Algorithm: PasswordHashBcrypt},
+	}
+
+	require.ErrorIs(t, ValidatePassword(cfg, strings.Repeat("a", 73)), ErrWeakPassword)
+}
+
+func TestPasswordPolicyMissingDenyList(t *testing.T) {
+	_, err := LoadPasswordPolicy(&config.Config{
+		PasswordPolicy: config.PasswordPolicy{DenyListFile: filepath.Join(t.TempDir(), "missing.txt")},
+	})
+	require.Error(t, err)
+}
@@ -1,22 +1,95 @@
 package utils
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
+
+	"github.com/mirasildev/note_project/config"
 )
 
+func newPasswordConfig(algorithm string) *config.Config {
+	return &config.Config{
+		PasswordHash: config.PasswordHash{
+			Algorithm:         algorithm,
+			BcryptCost:        4,
+			Argon2Memory:      1024,
+			Argon2Iterations:  1,
+			Argon2Parallelism: 1,
+		},
+	}
+}
+
 func TestPassword(t *testing.T) {
 	password := "1234567"
 
-	hashedPassword, err := HashPassword(password)
+	for _, algorithm := range []string{PasswordHashBcrypt, PasswordHashArgon2id} {
+		t.Run(algorithm, func(t *testing.T) {
+			cfg := newPasswordConfig(algorithm)
+
+			hashedPassword, err := HashPassword(cfg, password)
+			require.NoError(t, err)
+			require.NotEmpty(t, hashedPassword)
+
+			hashedPassword1, err := HashPassword(cfg, password)
+			require.NoError(t, err)
+			require.NotEqual(t, hashedPassword, hashedPassword1)
+
+			rehashed, err := CheckPassword(cfg, password, hashedPassword)
+			require.NoError(t, err)
+			require.Empty(t, rehashed)
+
+			_, err = CheckPassword(cfg, "wrong", hashedPassword)
+			require.ErrorIs(t, err, ErrPasswordMismatch)
+		})
+	}
+}
+
+func TestArgon2idEncoding(t *testing.T) {
+	hashedPassword, err := HashPassword(newPasswordConfig(PasswordHashArgon2id), "1234567")
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(hashedPassword, "$argon2id$v=19$m=1024,t=1,p=1$"))
+
+	_, err = CheckPassword(newPasswordConfig(PasswordHashArgon2id), "1234567", "$argon2id$v=19$m=1024$broken")
+	require.ErrorIs(t, err, ErrUnknownPasswordHash)
+}
+
+func TestPasswordRehash(t *testing.T) {
+	password := "1234567"
+
+	bcryptCfg := newPasswordConfig(PasswordHashBcrypt)
+	hashedPassword, err := HashPassword(bcryptCfg, password)
+	require.NoError(t, err)
+
+	// A higher cost makes the stored hash outdated
+	costlierCfg := newPasswordConfig(PasswordHashBcrypt)
+	costlierCfg.PasswordHash.BcryptCost = 5
+
+	rehashed, err := CheckPassword(costlierCfg, password, hashedPassword)
 	require.NoError(t, err)
-	require.NotEmpty(t, hashedPassword)
+	require.NotEmpty(t, rehashed)
 
-	hashedPassword1, err := HashPassword(password)
+	// So does another algorithm
+	argon2Cfg := newPasswordConfig(PasswordHashArgon2id)
+
+	rehashed, err = CheckPassword(argon2Cfg, password, hashedPassword)
 	require.NoError(t, err)
-	require.NotEmpty(t, hashedPassword1)
+	require.True(t, strings.HasPrefix(rehashed, "$argon2id$"))
 
-	err = CheckPassword(password, hashedPassword)
+	rehashedAgain, err := CheckPassword(argon2Cfg, password, rehashed)
 	require.NoError(t, err)
-}
\ No newline at end of file
+	require.Empty(t, rehashedAgain)
+
+	// And other argon2 parameters
+	argon2Cfg.PasswordHash.Argon2Iterations = 2
+
+	rehashedAgain, err = CheckPassword(argon2Cfg, password, rehashed)
+	require.NoError(t, err)
+	require.Contains(t, rehashedAgain, "t=2")
+
+	// Nothing is rehashed for a wrong password
+	rehashed, err = CheckPassword(argon2Cfg, "wrong", hashedPassword)
+	require.ErrorIs(t, err, ErrPasswordMismatch)
+	require.Empty(t, rehashed)
+}
@@ -2,8 +2,10 @@ package utils
 
 import (
 	"errors"
+	"strconv"
 	"time"
 
+	"github.com/golang-jwt/jwt"
 	"github.com/google/uuid"
 )
 
@@ -12,10 +14,31 @@ var (
 	ErrExpiredToken = errors.New("token has expired")
 )
 
+// Scopes limit what a token may access. A payload without scopes
+// belongs to an interactive login and has full access.
+const (
+	ScopeNotesRead  = "notes:read"
+	ScopeNotesWrite = "notes:write"
+	ScopeFilesWrite = "files:write"
+)
+
+var Scopes = []string{ScopeNotesRead, ScopeNotesWrite, ScopeFilesWrite}
+
+// Payload holds the registered claims, so other services can validate the
+// token with any JWT library, next to the claims of this service.
+// issued_at and expired_at are kept for clients which still read them.
 type Payload struct {
-	ID        uuid.UUID `json:"id"`
-	UserID    int64     `json:"user_id"`
-	Email     string    `json:"email"`
+	// StandardClaims.Id is left empty, ID below is the jti claim
+	jwt.StandardClaims
+	ID     uuid.UUID `json:"jti"`
+	UserID int64     `json:"user_id"`
+	Email  string    `json:"email"`
+	Role   string    `json:"role"`
+	Scopes []string  `json:"scopes,omitempty"`
+	// SessionID is the login the token was issued for, empty for personal access tokens
+	SessionID string `json:"sid,omitempty"`
+	// ClientID is the OAuth client the token was issued to, empty for first-party logins
+	ClientID  string    `json:"client_id,omitempty"`
 	IssuedAt  time.Time `json:"issued_at"`
 	ExpiredAt time.Time `json:"expired_at"`
 }
@@ -26,17 +49,52 @@ func NewPayload(params *TokenParams) (*Payload, error) {
 		return nil, err
 	}
 
+	now := time.Now()
+	expiredAt := now.Add(params.Duration)
+
 	payload := &Payload{
+		StandardClaims: jwt.StandardClaims{
+			Subject:   strconv.FormatInt(params.UserID, 10),
+			IssuedAt:  now.Unix(),
+			ExpiresAt: expiredAt.Unix(),
+		},
 		ID:        tokenID,
 		UserID:    params.UserID,
 		Email:     params.Email,
-		IssuedAt:  time.Now(),
-		ExpiredAt: time.Now().Add(params.Duration),
+		Role:      params.Role,
+		Scopes:    params.Scopes,
+		SessionID: params.SessionID,
+		ClientID:  params.ClientID,
+		IssuedAt:  now,
+		ExpiredAt: expiredAt,
 	}
 
 	return payload, nil
 }
 
+// HasScopes reports whether the payload grants all of the given scopes
+func (payload *Payload) HasScopes(scopes ...string) bool {
+	if len(payload.Scopes) == 0 {
+		return true
+	}
+
+	for _, scope := range scopes {
+		found := false
+		for _, s := range payload.Scopes {
+			if s == scope {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
 func (payload *Payload) Valid() error {
 	if time.Now().After(payload.ExpiredAt) {
 		return ErrExpiredToken
@@ -2,6 +2,7 @@ package utils
 
 import (
 	"crypto/rand"
+	"encoding/base64"
 	"io"
 )
 
@@ -19,4 +20,15 @@ func GenerateRandomCode(max int) (string, error) {
 	}
 
 	return string(b), nil
-}
\ No newline at end of file
+}
+
+// GenerateRandomToken returns a url-safe string built from n random bytes
+func GenerateRandomToken(n int) (string, error) {
+	b := make([]byte, n)
+	_, err := io.ReadFull(rand.Reader, b)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
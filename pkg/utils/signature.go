@@ -0,0 +1,20 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+)
+
+// Sign returns the url-safe HMAC-SHA256 of the values joined together
+func Sign(secret string, values ...string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strings.Join(values, "\n")))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature compares the signature with the one of the values in constant time
+func VerifySignature(secret, signature string, values ...string) bool {
+	return hmac.Equal([]byte(signature), []byte(Sign(secret, values...)))
+}
@@ -0,0 +1,15 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignature(t *testing.T) {
+	signature := Sign("secret", "test@example.com", "token")
+
+	require.True(t, VerifySignature("secret", signature, "test@example.com", "token"))
+	require.False(t, VerifySignature("secret", signature, "other@example.com", "token"))
+	require.False(t, VerifySignature("other", signature, "test@example.com", "token"))
+}
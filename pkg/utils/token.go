@@ -1,6 +1,8 @@
 package utils
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"time"
 
@@ -10,9 +12,13 @@ import (
 )
 
 type TokenParams struct {
-	UserID   int64
-	Email    string
-	Duration time.Duration
+	UserID    int64
+	Email     string
+	Role      string
+	Scopes    []string
+	SessionID string
+	ClientID  string
+	Duration  time.Duration
 }
 
 // CreateToken creates a new token
@@ -22,22 +28,26 @@ func CreateToken(cfg *config.Config, params *TokenParams) (string, *Payload, err
 		return "", payload, err
 	}
 
-	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, payload)
-	token, err := jwtToken.SignedString([]byte(cfg.AuthSecretKey))
+	payload.Issuer = cfg.Jwt.Issuer
+	payload.Audience = cfg.Jwt.Audience
+
+	keySet, err := LoadKeySet(cfg)
+	if err != nil {
+		return "", payload, err
+	}
+
+	token, err := keySet.sign(payload)
 	return token, payload, err
 }
 
 // VerifyToken checks if the token is valid or not
 func VerifyToken(cfg *config.Config, token string) (*Payload, error) {
-	keyFunc := func(token *jwt.Token) (interface{}, error) {
-		_, ok := token.Method.(*jwt.SigningMethodHMAC)
-		if !ok {
-			return nil, ErrInvalidToken
-		}
-		return []byte(cfg.AuthSecretKey), nil
+	keySet, err := LoadKeySet(cfg)
+	if err != nil {
+		return nil, err
 	}
 
-	jwtToken, err := jwt.ParseWithClaims(token, &Payload{}, keyFunc)
+	jwtToken, err := jwt.ParseWithClaims(token, &Payload{}, keySet.keyFunc)
 	if err != nil {
 		verr, ok := err.(*jwt.ValidationError)
 		if ok && errors.Is(verr.Inner, ErrExpiredToken) {
@@ -51,5 +61,20 @@ func VerifyToken(cfg *config.Config, token string) (*Payload, error) {
 		return nil, ErrInvalidToken
 	}
 
+	if cfg.Jwt.Issuer != "" && !payload.VerifyIssuer(cfg.Jwt.Issuer, true) {
+		return nil, ErrInvalidToken
+	}
+
+	if cfg.Jwt.Audience != "" && !payload.VerifyAudience(cfg.Jwt.Audience, true) {
+		return nil, ErrInvalidToken
+	}
+
 	return payload, nil
 }
+
+// HashToken returns the hex encoded sha256 of an opaque token,
+// so the token itself never has to be stored
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,225 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/mirasildev/note_project/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateToken(t *testing.T) {
+	cfg := &config.Config{AuthSecretKey: "secret"}
+
+	token, payload, err := CreateToken(cfg, &TokenParams{
+		UserID:    1,
+		Email:     "test@example.com",
+		SessionID: "session-1",
+		ClientID:  "client-1",
+		Duration:  time.Minute,
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+
+	verified, err := VerifyToken(cfg, token)
+	require.NoError(t, err)
+	require.Equal(t, payload.ID, verified.ID)
+	require.Equal(t, payload.UserID, verified.UserID)
+	require.Equal(t, "session-1", verified.SessionID)
+	require.Equal(t, "client-1", verified.ClientID)
+	require.WithinDuration(t, payload.IssuedAt, verified.IssuedAt, time.Millisecond)
+}
+
+func TestExpiredToken(t *testing.T) {
+	cfg := &config.Config{AuthSecretKey: "secret"}
+
+	token, _, err := CreateToken(cfg, &TokenParams{
+		UserID:   1,
+		Email:    "test@example.com",
+		Duration: -time.Minute,
+	})
+	require.NoError(t, err)
+
+	_, err = VerifyToken(cfg, token)
+	require.ErrorIs(t, err, ErrExpiredToken)
+}
+
+func TestTokenRegisteredClaims(t *testing.T) {
+	cfg := &config.Config{
+		AuthSecretKey: "secret",
+		Jwt: config.Jwt{
+			Issuer:   "http://localhost:8000",
+			Audience: "note_project",
+		},
+	}
+
+	token, payload, err := CreateToken(cfg, &TokenParams{
+		UserID:   7,
+		Email:    "test@example.com",
+		Duration: time.Minute,
+	})
+	require.NoError(t, err)
+
+	claims := jwt.MapClaims{}
+	_, _, err = new(jwt.Parser).ParseUnverified(token, claims)
+	require.NoError(t, err)
+	require.Equal(t, "7", claims["sub"])
+	require.Equal(t, payload.ID.String(), claims["jti"])
+	require.Equal(t, "http://localhost:8000", claims["iss"])
+	require.Equal(t, "note_project", claims["aud"])
+	require.Equal(t, float64(payload.ExpiredAt.Unix()), claims["exp"])
+	require.Equal(t, float64(payload.IssuedAt.Unix()), claims["iat"])
+	// kept for clients which read them
+	require.Contains(t, claims, "expired_at")
+	require.Contains(t, claims, "issued_at")
+
+	_, err = VerifyToken(cfg, token)
+	require.NoError(t, err)
+
+	otherAudience := *cfg
+	otherAudience.Jwt.Audience = "other_service"
+	_, err = VerifyToken(&otherAudience, token)
+	require.ErrorIs(t, err, ErrInvalidToken)
+
+	otherIssuer := *cfg
+	otherIssuer.Jwt.Issuer = "https://evil.example.com"
+	_, err = VerifyToken(&otherIssuer, token)
+	require.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestPayloadHasScopes(t *testing.T) {
+	full := &Payload{}
+	require.True(t, full.HasScopes(ScopeNotesWrite))
+
+	scoped := &Payload{Scopes: []string{ScopeNotesRead, ScopeFilesWrite}}
+	require.True(t, scoped.HasScopes(ScopeNotesRead))
+	require.True(t, scoped.HasScopes(ScopeNotesRead, ScopeFilesWrite))
+	require.False(t, scoped.HasScopes(ScopeNotesWrite))
+}
+
+func writeKeyFiles(t *testing.T, key crypto.Signer) (string, string) {
+	dir := t.TempDir()
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	publicDER, err := x509.MarshalPKIXPublicKey(key.Public())
+	require.NoError(t, err)
+
+	privateFile := filepath.Join(dir, "private.pem")
+	err = os.WriteFile(privateFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}), 0600)
+	require.NoError(t, err)
+
+	publicFile := filepath.Join(dir, "public.pem")
+	err = os.WriteFile(publicFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}), 0600)
+	require.NoError(t, err)
+
+	return privateFile, publicFile
+}
+
+func TestAsymmetricToken(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	for method, key := range map[string]crypto.Signer{
+		SigningMethodRS256: rsaKey,
+		SigningMethodEdDSA: edKey,
+	} {
+		t.Run(method, func(t *testing.T) {
+			privateFile, _ := writeKeyFiles(t, key)
+
+			cfg := &config.Config{Jwt: config.Jwt{
+				SigningMethod:  method,
+				KeyID:          "key-1",
+				PrivateKeyFile: privateFile,
+			}}
+
+			token, payload, err := CreateToken(cfg, &TokenParams{
+				UserID:   1,
+				Email:    "test@example.com",
+				Duration: time.Minute,
+			})
+			require.NoError(t, err)
+
+			verified, err := VerifyToken(cfg, token)
+			require.NoError(t, err)
+			require.Equal(t, payload.ID, verified.ID)
+
+			keySet, err := LoadKeySet(cfg)
+			require.NoError(t, err)
+
+			jwks := keySet.JWKS()
+			require.Len(t, jwks.Keys, 1)
+			require.Equal(t, "key-1", jwks.Keys[0].Kid)
+			require.Equal(t, method, jwks.Keys[0].Alg)
+
+			// A token signed with HMAC must not be accepted
+			_, err = VerifyToken(cfg, createHS256Token(t, "test@example.com"))
+			require.ErrorIs(t, err, ErrInvalidToken)
+		})
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	oldKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	_, newKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	oldPrivateFile, oldPublicFile := writeKeyFiles(t, oldKey)
+	newPrivateFile, _ := writeKeyFiles(t, newKey)
+
+	oldCfg := &config.Config{Jwt: config.Jwt{
+		SigningMethod:  SigningMethodRS256,
+		KeyID:          "old",
+		PrivateKeyFile: oldPrivateFile,
+	}}
+
+	oldToken, _, err := CreateToken(oldCfg, &TokenParams{UserID: 1, Duration: time.Minute})
+	require.NoError(t, err)
+
+	newCfg := &config.Config{Jwt: config.Jwt{
+		SigningMethod:  SigningMethodEdDSA,
+		KeyID:          "new",
+		PrivateKeyFile: newPrivateFile,
+		PublicKeyFiles: map[string]string{"old": oldPublicFile},
+	}}
+
+	_, err = VerifyToken(newCfg, oldToken)
+	require.NoError(t, err)
+
+	keySet, err := LoadKeySet(newCfg)
+	require.NoError(t, err)
+	require.Len(t, keySet.JWKS().Keys, 2)
+
+	// Once the old key is removed its tokens are rejected
+	retiredCfg := &config.Config{Jwt: newCfg.Jwt}
+	retiredCfg.Jwt.PublicKeyFiles = nil
+
+	_, err = VerifyToken(retiredCfg, oldToken)
+	require.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func createHS256Token(t *testing.T, email string) string {
+	token, _, err := CreateToken(&config.Config{AuthSecretKey: "secret"}, &TokenParams{
+		UserID:   1,
+		Email:    email,
+		Duration: time.Minute,
+	})
+	require.NoError(t, err)
+
+	return token
+}
@@ -0,0 +1,137 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"math/big"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// COSE algorithm identifiers
+const (
+	algES256 = -7
+	algEdDSA = -8
+	algRS256 = -257
+)
+
+// COSE key types and curves
+const (
+	ktyOKP = 1
+	ktyEC2 = 2
+	ktyRSA = 3
+
+	crvP256    = 1
+	crvEd25519 = 6
+)
+
+// Labels of the COSE key parameters. The negative labels mean
+// different things depending on the key type.
+const (
+	labelKty = 1
+	labelAlg = 3
+	labelCrv = -1
+	labelX   = -2
+	labelY   = -3
+	labelN   = -1
+	labelE   = -2
+)
+
+var ErrUnsupportedKey = errors.New("credential public key is not supported")
+
+type publicKey struct {
+	alg int
+	key interface{}
+}
+
+// parsePublicKey parses a COSE_Key of one of the algorithms offered at registration
+func parsePublicKey(data []byte) (*publicKey, error) {
+	var params map[int]interface{}
+	err := cbor.Unmarshal(data, &params)
+	if err != nil {
+		return nil, ErrUnsupportedKey
+	}
+
+	kty, _ := intParam(params, labelKty)
+	alg, _ := intParam(params, labelAlg)
+
+	switch {
+	case kty == ktyEC2 && alg == algES256:
+		crv, _ := intParam(params, labelCrv)
+		x, xOK := params[labelX].([]byte)
+		y, yOK := params[labelY].([]byte)
+		if crv != crvP256 || !xOK || !yOK {
+			return nil, ErrUnsupportedKey
+		}
+
+		key := &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}
+		if !key.Curve.IsOnCurve(key.X, key.Y) {
+			return nil, ErrUnsupportedKey
+		}
+
+		return &publicKey{alg: alg, key: key}, nil
+	case kty == ktyOKP && alg == algEdDSA:
+		crv, _ := intParam(params, labelCrv)
+		x, ok := params[labelX].([]byte)
+		if crv != crvEd25519 || !ok || len(x) != ed25519.PublicKeySize {
+			return nil, ErrUnsupportedKey
+		}
+
+		return &publicKey{alg: alg, key: ed25519.PublicKey(x)}, nil
+	case kty == ktyRSA && alg == algRS256:
+		n, nOK := params[labelN].([]byte)
+		e, eOK := params[labelE].([]byte)
+		if !nOK || !eOK {
+			return nil, ErrUnsupportedKey
+		}
+
+		return &publicKey{alg: alg, key: &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}}, nil
+	}
+
+	return nil, ErrUnsupportedKey
+}
+
+func (k *publicKey) verify(message, signature []byte) error {
+	switch key := k.key.(type) {
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(message)
+		if ecdsa.VerifyASN1(key, digest[:], signature) {
+			return nil
+		}
+	case ed25519.PublicKey:
+		if ed25519.Verify(key, message, signature) {
+			return nil
+		}
+	case *rsa.PublicKey:
+		digest := sha256.Sum256(message)
+		if rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) == nil {
+			return nil
+		}
+	}
+
+	return ErrInvalidSignature
+}
+
+// intParam reads an integer parameter, cbor decodes them as int64 or uint64
+func intParam(params map[int]interface{}, label int) (int, bool) {
+	switch v := params[label].(type) {
+	case int64:
+		return int(v), true
+	case uint64:
+		return int(v), true
+	}
+
+	return 0, false
+}
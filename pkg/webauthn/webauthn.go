@@ -0,0 +1,425 @@
+package webauthn
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+
+	"github.com/mirasildev/note_project/config"
+	"github.com/mirasildev/note_project/pkg/utils"
+)
+
+const (
+	ceremonyCreate = "webauthn.create"
+	ceremonyGet    = "webauthn.get"
+
+	// Timeout is how long the client has to complete a ceremony
+	Timeout = 5 * time.Minute
+)
+
+// Flags of the authenticator data
+const (
+	flagUserPresent            = 0x01
+	flagUserVerified           = 0x04
+	flagAttestedCredentialData = 0x40
+)
+
+var (
+	ErrInvalidClientData   = errors.New("client data is invalid")
+	ErrChallengeMismatch   = errors.New("challenge does not match")
+	ErrOriginMismatch      = errors.New("origin is not allowed")
+	ErrInvalidAuthData     = errors.New("authenticator data is invalid")
+	ErrRPIDMismatch        = errors.New("credential is not scoped to this relying party")
+	ErrUserNotPresent      = errors.New("user presence was not confirmed")
+	ErrUserNotVerified     = errors.New("user was not verified with a PIN or biometrics")
+	ErrInvalidSignature    = errors.New("assertion signature is invalid")
+	ErrSignCountNotGreater = errors.New("signature counter did not increase, the authenticator may be cloned")
+)
+
+// WebAuthn runs the registration and assertion ceremonies of one relying party.
+// Attestation statements are not verified, which is the same trust as
+// requesting "none" attestation.
+type WebAuthn struct {
+	rpID    string
+	rpName  string
+	origins []string
+}
+
+func New(cfg config.WebAuthn) *WebAuthn {
+	return &WebAuthn{
+		rpID:    cfg.RPID,
+		rpName:  cfg.RPName,
+		origins: cfg.Origins,
+	}
+}
+
+// User is the account a credential is registered for
+type User struct {
+	// ID is the user handle, it must not contain personal information
+	ID          []byte
+	Name        string
+	DisplayName string
+}
+
+// Credential is what has to be stored to verify assertions of a registered authenticator
+type Credential struct {
+	ID        []byte
+	PublicKey []byte
+	SignCount uint32
+}
+
+type RelyingParty struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type UserEntity struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+type CredentialParameter struct {
+	Type string `json:"type"`
+	Alg  int    `json:"alg"`
+}
+
+type CredentialDescriptor struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+type AuthenticatorSelection struct {
+	ResidentKey      string `json:"residentKey"`
+	UserVerification string `json:"userVerification"`
+}
+
+// CreationOptions are passed to navigator.credentials.create() as publicKey.
+// Binary values are base64url encoded.
+type CreationOptions struct {
+	Challenge              string                 `json:"challenge"`
+	RP                     RelyingParty           `json:"rp"`
+	User                   UserEntity             `json:"user"`
+	PubKeyCredParams       []CredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                  `json:"timeout"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection AuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                 `json:"attestation"`
+}
+
+// RequestOptions are passed to navigator.credentials.get() as publicKey.
+// Binary values are base64url encoded.
+type RequestOptions struct {
+	Challenge        string                 `json:"challenge"`
+	Timeout          int64                  `json:"timeout"`
+	RPID             string                 `json:"rpId"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                 `json:"userVerification"`
+}
+
+// AttestationResponse is the PublicKeyCredential returned by
+// navigator.credentials.create() with binary values base64url encoded
+type AttestationResponse struct {
+	ID       string `json:"id" binding:"required"`
+	Type     string `json:"type" binding:"required,eq=public-key"`
+	Response struct {
+		ClientDataJSON    string `json:"clientDataJSON" binding:"required"`
+		AttestationObject string `json:"attestationObject" binding:"required"`
+	} `json:"response" binding:"required"`
+}
+
+// AssertionResponse is the PublicKeyCredential returned by
+// navigator.credentials.get() with binary values base64url encoded
+type AssertionResponse struct {
+	ID       string `json:"id" binding:"required"`
+	Type     string `json:"type" binding:"required,eq=public-key"`
+	Response struct {
+		ClientDataJSON    string `json:"clientDataJSON" binding:"required"`
+		AuthenticatorData string `json:"authenticatorData" binding:"required"`
+		Signature         string `json:"signature" binding:"required"`
+		UserHandle        string `json:"userHandle"`
+	} `json:"response" binding:"required"`
+}
+
+type clientData struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Origin    string `json:"origin"`
+}
+
+type attestationObject struct {
+	Fmt      string          `cbor:"fmt"`
+	AttStmt  cbor.RawMessage `cbor:"attStmt"`
+	AuthData []byte          `cbor:"authData"`
+}
+
+type authenticatorData struct {
+	RPIDHash     []byte
+	Flags        byte
+	SignCount    uint32
+	CredentialID []byte
+	PublicKey    []byte
+}
+
+// NewChallenge returns a random base64url encoded challenge
+func NewChallenge() (string, error) {
+	return utils.GenerateRandomToken(32)
+}
+
+// BeginRegistration returns the options of a registration ceremony.
+// The authenticators of the exclude credentials are not registered twice.
+func (w *WebAuthn) BeginRegistration(challenge string, user *User, exclude [][]byte) *CreationOptions {
+	return &CreationOptions{
+		Challenge: challenge,
+		RP: RelyingParty{
+			ID:   w.rpID,
+			Name: w.rpName,
+		},
+		User: UserEntity{
+			ID:          base64.RawURLEncoding.EncodeToString(user.ID),
+			Name:        user.Name,
+			DisplayName: user.DisplayName,
+		},
+		PubKeyCredParams: []CredentialParameter{
+			{Type: "public-key", Alg: algES256},
+			{Type: "public-key", Alg: algEdDSA},
+			{Type: "public-key", Alg: algRS256},
+		},
+		Timeout:            Timeout.Milliseconds(),
+		ExcludeCredentials: descriptors(exclude),
+		AuthenticatorSelection: AuthenticatorSelection{
+			ResidentKey:      "preferred",
+			UserVerification: "required",
+		},
+		Attestation: "none",
+	}
+}
+
+// BeginLogin returns the options of an assertion ceremony. Without allowed
+// credentials the user picks any passkey of this relying party.
+func (w *WebAuthn) BeginLogin(challenge string, allow [][]byte) *RequestOptions {
+	return &RequestOptions{
+		Challenge:        challenge,
+		Timeout:          Timeout.Milliseconds(),
+		RPID:             w.rpID,
+		AllowCredentials: descriptors(allow),
+		UserVerification: "required",
+	}
+}
+
+func descriptors(ids [][]byte) []CredentialDescriptor {
+	result := make([]CredentialDescriptor, 0, len(ids))
+	for _, id := range ids {
+		result = append(result, CredentialDescriptor{
+			Type: "public-key",
+			ID:   base64.RawURLEncoding.EncodeToString(id),
+		})
+	}
+
+	return result
+}
+
+// FinishRegistration verifies the response of navigator.credentials.create()
+// and returns the new credential
+func (w *WebAuthn) FinishRegistration(challenge string, resp *AttestationResponse) (*Credential, error) {
+	rawClientData, err := decode(resp.Response.ClientDataJSON)
+	if err != nil {
+		return nil, ErrInvalidClientData
+	}
+
+	err = w.verifyClientData(rawClientData, ceremonyCreate, challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	rawAttestation, err := decode(resp.Response.AttestationObject)
+	if err != nil {
+		return nil, ErrInvalidAuthData
+	}
+
+	var attestation attestationObject
+	err = cbor.Unmarshal(rawAttestation, &attestation)
+	if err != nil {
+		return nil, ErrInvalidAuthData
+	}
+
+	authData, err := w.parseAuthData(attestation.AuthData)
+	if err != nil {
+		return nil, err
+	}
+
+	if authData.Flags&flagAttestedCredentialData == 0 {
+		return nil, ErrInvalidAuthData
+	}
+
+	_, err = parsePublicKey(authData.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Credential{
+		ID:        authData.CredentialID,
+		PublicKey: authData.PublicKey,
+		SignCount: authData.SignCount,
+	}, nil
+}
+
+// FinishLogin verifies the response of navigator.credentials.get() made with
+// the credential and returns the new value of its signature counter
+func (w *WebAuthn) FinishLogin(challenge string, credential *Credential, resp *AssertionResponse) (uint32, error) {
+	rawClientData, err := decode(resp.Response.ClientDataJSON)
+	if err != nil {
+		return 0, ErrInvalidClientData
+	}
+
+	err = w.verifyClientData(rawClientData, ceremonyGet, challenge)
+	if err != nil {
+		return 0, err
+	}
+
+	rawAuthData, err := decode(resp.Response.AuthenticatorData)
+	if err != nil {
+		return 0, ErrInvalidAuthData
+	}
+
+	authData, err := w.parseAuthData(rawAuthData)
+	if err != nil {
+		return 0, err
+	}
+
+	signature, err := decode(resp.Response.Signature)
+	if err != nil {
+		return 0, ErrInvalidSignature
+	}
+
+	publicKey, err := parsePublicKey(credential.PublicKey)
+	if err != nil {
+		return 0, err
+	}
+
+	clientDataHash := sha256.Sum256(rawClientData)
+	signed := make([]byte, 0, len(rawAuthData)+len(clientDataHash))
+	signed = append(signed, rawAuthData...)
+	signed = append(signed, clientDataHash[:]...)
+
+	err = publicKey.verify(signed, signature)
+	if err != nil {
+		return 0, err
+	}
+
+	// Authenticators which don't count signatures always report zero
+	if (authData.SignCount != 0 || credential.SignCount != 0) && authData.SignCount <= credential.SignCount {
+		return 0, ErrSignCountNotGreater
+	}
+
+	return authData.SignCount, nil
+}
+
+// ChallengeOf returns the challenge the client data of a response was made for
+func ChallengeOf(clientDataJSON string) (string, error) {
+	raw, err := decode(clientDataJSON)
+	if err != nil {
+		return "", ErrInvalidClientData
+	}
+
+	var data clientData
+	err = json.Unmarshal(raw, &data)
+	if err != nil {
+		return "", ErrInvalidClientData
+	}
+
+	return data.Challenge, nil
+}
+
+func (w *WebAuthn) verifyClientData(raw []byte, ceremony, challenge string) error {
+	var data clientData
+	err := json.Unmarshal(raw, &data)
+	if err != nil || data.Type != ceremony {
+		return ErrInvalidClientData
+	}
+
+	if subtle.ConstantTimeCompare([]byte(data.Challenge), []byte(challenge)) != 1 {
+		return ErrChallengeMismatch
+	}
+
+	for _, origin := range w.origins {
+		if data.Origin == origin {
+			return nil
+		}
+	}
+
+	return ErrOriginMismatch
+}
+
+// parseAuthData parses the authenticator data and checks that it belongs to
+// this relying party and that the user was present and verified. A passkey
+// replaces both the password and the second factor, so presence alone,
+// which anyone holding the key can give, is not enough
+func (w *WebAuthn) parseAuthData(data []byte) (*authenticatorData, error) {
+	// rpIdHash (32) | flags (1) | signCount (4)
+	if len(data) < 37 {
+		return nil, ErrInvalidAuthData
+	}
+
+	result := &authenticatorData{
+		RPIDHash:  data[:32],
+		Flags:     data[32],
+		SignCount: binary.BigEndian.Uint32(data[33:37]),
+	}
+
+	rpIDHash := sha256.Sum256([]byte(w.rpID))
+	if !bytes.Equal(result.RPIDHash, rpIDHash[:]) {
+		return nil, ErrRPIDMismatch
+	}
+
+	if result.Flags&flagUserPresent == 0 {
+		return nil, ErrUserNotPresent
+	}
+
+	if result.Flags&flagUserVerified == 0 {
+		return nil, ErrUserNotVerified
+	}
+
+	if result.Flags&flagAttestedCredentialData == 0 {
+		return result, nil
+	}
+
+	// aaguid (16) | credentialIdLength (2) | credentialId | credentialPublicKey
+	rest := data[37:]
+	if len(rest) < 18 {
+		return nil, ErrInvalidAuthData
+	}
+
+	idLength := int(binary.BigEndian.Uint16(rest[16:18]))
+	rest = rest[18:]
+	if len(rest) < idLength {
+		return nil, ErrInvalidAuthData
+	}
+
+	result.CredentialID = rest[:idLength]
+	rest = rest[idLength:]
+
+	// The public key may be followed by extensions, so only the first item is read
+	var publicKey cbor.RawMessage
+	decoder := cbor.NewDecoder(bytes.NewReader(rest))
+	err := decoder.Decode(&publicKey)
+	if err != nil {
+		return nil, ErrInvalidAuthData
+	}
+
+	result.PublicKey = []byte(publicKey)
+	return result, nil
+}
+
+// decode accepts base64url with or without padding, browsers differ in what they send
+func decode(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
@@ -0,0 +1,302 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mirasildev/note_project/config"
+)
+
+const (
+	testRPID   = "localhost"
+	testOrigin = "http://localhost:8000"
+)
+
+// softAuthenticator behaves like a platform authenticator
+// together with the browser calling it
+type softAuthenticator struct {
+	rpID         string
+	origin       string
+	credentialID []byte
+	key          crypto.Signer
+	signCount    uint32
+	// flags are reported in the authenticator data of assertions
+	flags byte
+}
+
+func newSoftAuthenticator(t *testing.T, key crypto.Signer) *softAuthenticator {
+	credentialID := make([]byte, 16)
+	_, err := rand.Read(credentialID)
+	require.NoError(t, err)
+
+	return &softAuthenticator{
+		rpID:         testRPID,
+		origin:       testOrigin,
+		credentialID: credentialID,
+		key:          key,
+		flags:        flagUserPresent | flagUserVerified,
+	}
+}
+
+func (a *softAuthenticator) coseKey(t *testing.T) []byte {
+	var params map[int]interface{}
+
+	switch key := a.key.Public().(type) {
+	case *ecdsa.PublicKey:
+		params = map[int]interface{}{
+			labelKty: ktyEC2,
+			labelAlg: algES256,
+			labelCrv: crvP256,
+			labelX:   key.X.FillBytes(make([]byte, 32)),
+			labelY:   key.Y.FillBytes(make([]byte, 32)),
+		}
+	case ed25519.PublicKey:
+		params = map[int]interface{}{
+			labelKty: ktyOKP,
+			labelAlg: algEdDSA,
+			labelCrv: crvEd25519,
+			labelX:   []byte(key),
+		}
+	}
+
+	data, err := cbor.Marshal(params)
+	require.NoError(t, err)
+
+	return data
+}
+
+func (a *softAuthenticator) authData(flags byte, attested []byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(a.rpID))
+
+	data := append([]byte{}, rpIDHash[:]...)
+	data = append(data, flags)
+	data = binary.BigEndian.AppendUint32(data, a.signCount)
+	return append(data, attested...)
+}
+
+func (a *softAuthenticator) clientData(t *testing.T, ceremony, challenge string) []byte {
+	data, err := json.Marshal(map[string]string{
+		"type":      ceremony,
+		"challenge": challenge,
+		"origin":    a.origin,
+	})
+	require.NoError(t, err)
+
+	return data
+}
+
+func (a *softAuthenticator) create(t *testing.T, challenge string) *AttestationResponse {
+	// aaguid | credentialIdLength | credentialId | credentialPublicKey
+	attested := make([]byte, 16)
+	attested = binary.BigEndian.AppendUint16(attested, uint16(len(a.credentialID)))
+	attested = append(attested, a.credentialID...)
+	attested = append(attested, a.coseKey(t)...)
+
+	attestation, err := cbor.Marshal(map[string]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[string]interface{}{},
+		"authData": a.authData(flagUserPresent|flagUserVerified|flagAttestedCredentialData, attested),
+	})
+	require.NoError(t, err)
+
+	var resp AttestationResponse
+	resp.ID = encode(a.credentialID)
+	resp.Type = "public-key"
+	resp.Response.ClientDataJSON = encode(a.clientData(t, ceremonyCreate, challenge))
+	resp.Response.AttestationObject = encode(attestation)
+
+	return &resp
+}
+
+func (a *softAuthenticator) get(t *testing.T, challenge string) *AssertionResponse {
+	a.signCount++
+
+	authData := a.authData(a.flags, nil)
+	clientData := a.clientData(t, ceremonyGet, challenge)
+	clientDataHash := sha256.Sum256(clientData)
+
+	message := append(append([]byte{}, authData...), clientDataHash[:]...)
+
+	var signature []byte
+	var err error
+	switch key := a.key.(type) {
+	case *ecdsa.PrivateKey:
+		digest := sha256.Sum256(message)
+		signature, err = ecdsa.SignASN1(rand.Reader, key, digest[:])
+	case ed25519.PrivateKey:
+		signature = ed25519.Sign(key, message)
+	}
+	require.NoError(t, err)
+
+	var resp AssertionResponse
+	resp.ID = encode(a.credentialID)
+	resp.Type = "public-key"
+	resp.Response.ClientDataJSON = encode(clientData)
+	resp.Response.AuthenticatorData = encode(authData)
+	resp.Response.Signature = encode(signature)
+
+	return &resp
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func newTestWebAuthn() *WebAuthn {
+	return New(config.WebAuthn{
+		RPID:    testRPID,
+		RPName:  "Note",
+		Origins: []string{testOrigin},
+	})
+}
+
+func newChallenge(t *testing.T) string {
+	challenge, err := NewChallenge()
+	require.NoError(t, err)
+
+	return challenge
+}
+
+func register(t *testing.T, w *WebAuthn, a *softAuthenticator) *Credential {
+	challenge := newChallenge(t)
+
+	credential, err := w.FinishRegistration(challenge, a.create(t, challenge))
+	require.NoError(t, err)
+	require.Equal(t, a.credentialID, credential.ID)
+
+	return credential
+}
+
+func TestRegistrationAndLogin(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	for name, key := range map[string]crypto.Signer{"ES256": ecKey, "EdDSA": edKey} {
+		t.Run(name, func(t *testing.T) {
+			w := newTestWebAuthn()
+			a := newSoftAuthenticator(t, key)
+			credential := register(t, w, a)
+
+			challenge := newChallenge(t)
+			resp := a.get(t, challenge)
+
+			responseChallenge, err := ChallengeOf(resp.Response.ClientDataJSON)
+			require.NoError(t, err)
+			require.Equal(t, challenge, responseChallenge)
+
+			signCount, err := w.FinishLogin(challenge, credential, resp)
+			require.NoError(t, err)
+			require.Equal(t, uint32(1), signCount)
+		})
+	}
+}
+
+func TestRegistrationFromOtherOrigin(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	w := newTestWebAuthn()
+	a := newSoftAuthenticator(t, key)
+	a.origin = "https://evil.example.com"
+
+	challenge := newChallenge(t)
+	_, err = w.FinishRegistration(challenge, a.create(t, challenge))
+	require.ErrorIs(t, err, ErrOriginMismatch)
+}
+
+func TestRegistrationForOtherRelyingParty(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	w := newTestWebAuthn()
+	a := newSoftAuthenticator(t, key)
+	a.rpID = "example.com"
+
+	challenge := newChallenge(t)
+	_, err = w.FinishRegistration(challenge, a.create(t, challenge))
+	require.ErrorIs(t, err, ErrRPIDMismatch)
+}
+
+func TestLoginWithWrongChallenge(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	w := newTestWebAuthn()
+	a := newSoftAuthenticator(t, key)
+	credential := register(t, w, a)
+
+	_, err = w.FinishLogin(newChallenge(t), credential, a.get(t, newChallenge(t)))
+	require.ErrorIs(t, err, ErrChallengeMismatch)
+}
+
+func TestLoginWithOtherKey(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	w := newTestWebAuthn()
+	a := newSoftAuthenticator(t, key)
+	credential := register(t, w, a)
+
+	a.key = otherKey
+
+	challenge := newChallenge(t)
+	_, err = w.FinishLogin(challenge, credential, a.get(t, challenge))
+	require.ErrorIs(t, err, ErrInvalidSignature)
+}
+
+func TestLoginWithClonedAuthenticator(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	w := newTestWebAuthn()
+	a := newSoftAuthenticator(t, key)
+	credential := register(t, w, a)
+	credential.SignCount = 5
+
+	challenge := newChallenge(t)
+	_, err = w.FinishLogin(challenge, credential, a.get(t, challenge))
+	require.ErrorIs(t, err, ErrSignCountNotGreater)
+}
+
+func TestLoginWithoutUserVerification(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	w := newTestWebAuthn()
+	a := newSoftAuthenticator(t, key)
+	credential := register(t, w, a)
+
+	// a security key touched without entering its PIN
+	a.flags = flagUserPresent
+
+	challenge := newChallenge(t)
+	_, err = w.FinishLogin(challenge, credential, a.get(t, challenge))
+	require.ErrorIs(t, err, ErrUserNotVerified)
+}
+
+func TestBeginCeremoniesRequireUserVerification(t *testing.T) {
+	w := newTestWebAuthn()
+
+	registration := w.BeginRegistration(newChallenge(t), &User{ID: []byte{1}, Name: "user"}, nil)
+	require.Equal(t, "required", registration.AuthenticatorSelection.UserVerification)
+
+	login := w.BeginLogin(newChallenge(t), nil)
+	require.Equal(t, "required", login.UserVerification)
+}
@@ -2,15 +2,21 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/go-redis/redis/v9"
 )
 
+var ErrKeyNotFound = errors.New("key not found")
 
 type InMemoryStorageI interface{
 	Set(key, value string, exp time.Duration) error
 	Get(key string) (string, error)
+	Delete(key string) error
+	SetNX(key, value string, exp time.Duration) (bool, error)
+	Incr(key string, exp time.Duration) (int64, error)
+	TTL(key string) (time.Duration, error)
 }
 
 type storageRedis struct {
@@ -34,9 +40,63 @@ func (r *storageRedis) Set(key, value string, exp time.Duration) error {
 
 func (r *storageRedis) Get(key string) (string, error) {
 	val, err := r.client.Get(context.Background(), key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", ErrKeyNotFound
+	}
 	if err != nil {
 		return "", err
 	}
 
 	return val, nil
-}
\ No newline at end of file
+}
+
+func (r *storageRedis) Delete(key string) error {
+	err := r.client.Del(context.Background(), key).Err()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SetNX sets the key only if it does not exist yet and reports whether it was set
+func (r *storageRedis) SetNX(key, value string, exp time.Duration) (bool, error) {
+	ok, err := r.client.SetNX(context.Background(), key, value, exp).Result()
+	if err != nil {
+		return false, err
+	}
+
+	return ok, nil
+}
+
+// Incr increments the counter stored at key. The expiration is set
+// when the counter is created and is not extended afterwards.
+func (r *storageRedis) Incr(key string, exp time.Duration) (int64, error) {
+	val, err := r.client.Incr(context.Background(), key).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	if val == 1 {
+		err = r.client.Expire(context.Background(), key, exp).Err()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return val, nil
+}
+
+// TTL returns the remaining time to live of the key, or 0 if the key doesn't exist
+func (r *storageRedis) TTL(key string) (time.Duration, error) {
+	ttl, err := r.client.TTL(context.Background(), key).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	if ttl < 0 {
+		return 0, nil
+	}
+
+	return ttl, nil
+}
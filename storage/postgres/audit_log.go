@@ -0,0 +1,134 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/mirasildev/note_project/storage/repo"
+)
+
+type auditLogRepo struct {
+	db *sqlx.DB
+}
+
+func NewAuditLog(db *sqlx.DB) repo.AuditLogStorageI {
+	return &auditLogRepo{
+		db: db,
+	}
+}
+
+func (ar *auditLogRepo) Create(l *repo.AuditLog) (*repo.AuditLog, error) {
+	query := `
+		INSERT INTO audit_logs(
+			user_id,
+			email,
+			event,
+			ip_address,
+			user_agent
+		) VALUES($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+
+	err := ar.db.QueryRow(
+		query,
+		l.UserID,
+		l.Email,
+		l.Event,
+		l.IPAddress,
+		l.UserAgent,
+	).Scan(&l.ID, &l.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+func (ar *auditLogRepo) GetAll(params *repo.GetAllAuditLogsParams) (*repo.GetAllAuditLogsResult, error) {
+	result := repo.GetAllAuditLogsResult{
+		AuditLogs: make([]*repo.AuditLog, 0),
+	}
+
+	var (
+		conditions []string
+		args       []interface{}
+	)
+	addCondition := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if params.UserID != nil {
+		addCondition("user_id=$%d", *params.UserID)
+	}
+	if params.Event != "" {
+		addCondition("event=$%d", params.Event)
+	}
+	if params.From != nil {
+		addCondition("created_at >= $%d", *params.From)
+	}
+	if params.To != nil {
+		addCondition("created_at < $%d", *params.To)
+	}
+
+	filter := ""
+	if len(conditions) > 0 {
+		filter = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	offset := (params.Page - 1) * params.Limit
+	limit := fmt.Sprintf(" LIMIT %d OFFSET %d", params.Limit, offset)
+
+	query := `
+		SELECT
+			id,
+			user_id,
+			email,
+			event,
+			ip_address,
+			user_agent,
+			created_at
+		FROM audit_logs
+		` + filter + `
+		ORDER BY created_at desc, id desc
+		` + limit
+
+	rows, err := ar.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			l     repo.AuditLog
+			email sql.NullString
+		)
+
+		err := rows.Scan(
+			&l.ID,
+			&l.UserID,
+			&email,
+			&l.Event,
+			&l.IPAddress,
+			&l.UserAgent,
+			&l.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		l.Email = email.String
+
+		result.AuditLogs = append(result.AuditLogs, &l)
+	}
+
+	queryCount := `SELECT count(1) FROM audit_logs` + filter
+	err = ar.db.QueryRow(queryCount, args...).Scan(&result.Count)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
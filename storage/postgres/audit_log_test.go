@@ -0,0 +1,59 @@
+package postgres_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-faker/faker/v4"
+	"github.com/mirasildev/note_project/storage/repo"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditLog(t *testing.T) {
+	u := createUser(t)
+	defer deleteUser(u.ID, t)
+
+	from := time.Now().Add(-time.Minute)
+
+	for _, event := range []string{repo.AuditEventLoginFailed, repo.AuditEventLoginSucceeded} {
+		l, err := strg.AuditLog().Create(&repo.AuditLog{
+			UserID:    &u.ID,
+			Email:     u.Email,
+			Event:     event,
+			IPAddress: faker.IPv4(),
+			UserAgent: faker.Word(),
+		})
+		require.NoError(t, err)
+		require.NotZero(t, l.ID)
+	}
+
+	result, err := strg.AuditLog().GetAll(&repo.GetAllAuditLogsParams{
+		Limit:  10,
+		Page:   1,
+		UserID: &u.ID,
+		From:   &from,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int32(2), result.Count)
+	require.Equal(t, repo.AuditEventLoginSucceeded, result.AuditLogs[0].Event)
+
+	result, err = strg.AuditLog().GetAll(&repo.GetAllAuditLogsParams{
+		Limit:  10,
+		Page:   1,
+		UserID: &u.ID,
+		Event:  repo.AuditEventLoginFailed,
+	})
+	require.NoError(t, err)
+	require.Len(t, result.AuditLogs, 1)
+	require.Equal(t, u.Email, result.AuditLogs[0].Email)
+
+	to := from
+	result, err = strg.AuditLog().GetAll(&repo.GetAllAuditLogsParams{
+		Limit:  10,
+		Page:   1,
+		UserID: &u.ID,
+		To:     &to,
+	})
+	require.NoError(t, err)
+	require.Empty(t, result.AuditLogs)
+}
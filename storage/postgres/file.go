@@ -0,0 +1,39 @@
+package postgres
+
+import (
+	"github.com/jmoiron/sqlx"
+	"github.com/mirasildev/note_project/storage/repo"
+)
+
+type fileRepo struct {
+	db *sqlx.DB
+}
+
+func NewFile(db *sqlx.DB) repo.FileStorageI {
+	return &fileRepo{
+		db: db,
+	}
+}
+
+func (fr *fileRepo) Create(f *repo.File) (*repo.File, error) {
+	query := `
+		INSERT INTO files(
+			id,
+			user_id,
+			path
+		) VALUES($1, $2, $3)
+		RETURNING created_at
+	`
+
+	err := fr.db.QueryRow(
+		query,
+		f.ID,
+		f.UserID,
+		f.Path,
+	).Scan(&f.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
@@ -2,9 +2,14 @@ package postgres
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+	"html"
+	"strings"
+	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"github.com/mirasildev/note_project/storage/repo"
 )
 
@@ -22,24 +27,47 @@ func (nt *noteRepo) Create(n *repo.Note) (*repo.Note, error) {
 	query := `
 		INSERT INTO notes(
 			user_id,
+			notebook_id,
 			title,
 			description,
 			created_at,
 			updated_at,
 			deleted_at
-		) VALUES($1, $2, $3, $4, $5, $6)
-		RETURNING id, created_at
+		) VALUES($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at, version
 	`
 
-	err := nt.db.QueryRow(
+	tx, err := nt.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(
 		query,
 		n.UserID,
+		n.NotebookID,
 		n.Title,
 		n.Description,
 		n.CreatedAt,
 		n.UpdatedAt,
 		n.DeletedAt,
-	).Scan(&n.ID, &n.CreatedAt)
+	).Scan(&n.ID, &n.CreatedAt, &n.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	err = insertNoteRevision(tx, n)
+	if err != nil {
+		return nil, err
+	}
+
+	n.Tags, err = setNoteTags(tx, n.ID, n.UserID, n.Tags)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
 	if err != nil {
 		return nil, err
 	}
@@ -47,29 +75,35 @@ func (nt *noteRepo) Create(n *repo.Note) (*repo.Note, error) {
 	return n, nil
 }
 
-func (nt *noteRepo) Get(id int64) (*repo.Note, error) {
+func (nt *noteRepo) Get(id, userID int64) (*repo.Note, error) {
 	var result repo.Note
 
 	query := `
 		SELECT 
 			id,
 			user_id,
+			notebook_id,
 			title,
 			description,
 			created_at,
-			updated_at
+			updated_at,
+			version,
+			` + noteTagsColumn("notes") + `
 		FROM notes
-		WHERE id=$1 AND deleted_at IS NULL
+		WHERE id=$1 AND user_id=$2 AND deleted_at IS NULL
 	`
 
-	row := nt.db.QueryRow(query, id)
+	row := nt.db.QueryRow(query, id, userID)
 	err := row.Scan(
 		&result.ID,
 		&result.UserID,
+		&result.NotebookID,
 		&result.Title,
 		&result.Description,
 		&result.CreatedAt,
 		&result.UpdatedAt,
+		&result.Version,
+		pq.Array(&result.Tags),
 	)
 	if err != nil {
 		return nil, err
@@ -78,88 +112,227 @@ func (nt *noteRepo) Get(id int64) (*repo.Note, error) {
 	return &result, nil
 }
 
+const (
+	noteColumns = "id, user_id, notebook_id, title, description, created_at, updated_at, deleted_at, version"
+
+	// ts_headline marks the matches with control characters instead of tags,
+	// the headline is HTML escaped before they become <mark> tags
+	headlineStartSel = "\x02"
+	headlineStopSel  = "\x03"
+
+	titleHeadlineOptions       = "HighlightAll=true, StartSel=" + headlineStartSel + ", StopSel=" + headlineStopSel
+	descriptionHeadlineOptions = "MaxFragments=2, MaxWords=20, MinWords=5, StartSel=" + headlineStartSel + ", StopSel=" + headlineStopSel
+
+	// headlineText is the column without the selection characters,
+	// so a note can't mark its own text
+	headlineText = "translate(%s, chr(2) || chr(3), '')"
+)
+
+var headlineReplacer = strings.NewReplacer(headlineStartSel, "<mark>", headlineStopSel, "</mark>")
+
+// highlight escapes the headline and wraps the matches in <mark> tags
+func highlight(headline string) string {
+	return headlineReplacer.Replace(html.EscapeString(headline))
+}
+
 func (nt *noteRepo) GetAllNotes(params *repo.GetAllNotesParams) (*repo.GetAllNotesResult, error) {
+	return nt.getNotes(params, "deleted_at IS NULL", "created_at desc")
+}
+
+func (nt *noteRepo) GetTrash(params *repo.GetAllNotesParams) (*repo.GetAllNotesResult, error) {
+	return nt.getNotes(params, "deleted_at IS NOT NULL", "deleted_at desc")
+}
+
+// getNotes lists the notes of the user matching the condition. With a search
+// the notes are ranked by relevance and the snippets are highlighted
+// only for the page which is returned
+func (nt *noteRepo) getNotes(params *repo.GetAllNotesParams, condition, orderBy string) (*repo.GetAllNotesResult, error) {
 	result := repo.GetAllNotesResult{
 		Notes: make([]*repo.Note, 0),
 	}
 
-	offset := (params.Page - 1) * params.Limit
-	limit := fmt.Sprintf(" LIMIT %d OFFSET %d", params.Limit, offset)
-	filter := ""
+	args := []interface{}{params.UserID}
+	where := " WHERE user_id=$1 AND " + condition
+	if len(params.Tags) > 0 {
+		args = append(args, pq.Array(params.Tags))
+		where += " AND " + tagsCondition(len(args), params.TagsMatchAll)
+	}
+
+	if params.NotebookID != nil {
+		args = append(args, *params.NotebookID)
+		where += " AND " + notebookCondition(len(args), params.Recursive)
+	}
+
+	from := " FROM notes" + where
 	if params.Search != "" {
-		str := "%" + fmt.Sprint(params.UserID) + "%"
-		filter += fmt.Sprintf(
-			` WHERE user_id='%s' AND deleted_at IS NULL`,
-			str,
-		)
+		args = append(args, params.Search)
+		from = fmt.Sprintf(" FROM notes, websearch_to_tsquery('english', $%d) q", len(args)) +
+			where + " AND search_vector @@ q"
 	}
 
-	query := `
-		SELECT 
-			id,
-			user_id,
-			title,
-			description,
-			created_at,
-			updated_at
-		FROM notes
-		` + filter + `
-		ORDER BY created_at desc
-		` + limit
+	offset := (params.Page - 1) * params.Limit
+	limit := fmt.Sprintf(" LIMIT $%d OFFSET $%d", len(args)+1, len(args)+2)
+
+	query := "SELECT " + noteColumns + ", " + noteTagsColumn("notes") + ", NULL, NULL" +
+		from + " ORDER BY " + orderBy + limit
+	if params.Search != "" {
+		query = `
+			SELECT
+				` + noteColumns + `,
+				` + noteTagsColumn("n") + `,
+				ts_headline('english', ` + fmt.Sprintf(headlineText, "title") + `, q, '` + titleHeadlineOptions + `'),
+				ts_headline('english', ` + fmt.Sprintf(headlineText, "description") + `, q, '` + descriptionHeadlineOptions + `')
+			FROM (
+				SELECT ` + noteColumns + `, q, ts_rank_cd(search_vector, q) AS rank
+				` + from + `
+				ORDER BY rank desc, ` + orderBy + limit + `
+			) n
+			ORDER BY rank desc, ` + orderBy
+	}
 
-	rows, err := nt.db.Query(query)
+	rows, err := nt.db.Query(query, append(args, params.Limit, offset)...)
 	if err != nil {
 		return nil, err
 	}
 
+	defer rows.Close()
+
 	for rows.Next() {
-		var note repo.Note
+		var (
+			note                 repo.Note
+			titleHighlight       *string
+			descriptionHighlight *string
+		)
 
 		err := rows.Scan(
 			&note.ID,
 			&note.UserID,
+			&note.NotebookID,
 			&note.Title,
 			&note.Description,
 			&note.CreatedAt,
 			&note.UpdatedAt,
+			&note.DeletedAt,
+			&note.Version,
+			pq.Array(&note.Tags),
+			&titleHighlight,
+			&descriptionHighlight,
 		)
 		if err != nil {
 			return nil, err
 		}
 
+		if titleHighlight != nil && descriptionHighlight != nil {
+			note.Highlight = &repo.NoteHighlight{
+				Title:       highlight(*titleHighlight),
+				Description: highlight(*descriptionHighlight),
+			}
+		}
+
 		result.Notes = append(result.Notes, &note)
 	}
 
+	queryCount := "SELECT count(1)" + from
+	err = nt.db.QueryRow(queryCount, args...).Scan(&result.Count)
+	if err != nil {
+		return nil, err
+	}
+
 	return &result, nil
 }
 
+// tagsCondition matches the notes with any or all of the tags
+// passed as the array parameter with the given number
+func tagsCondition(param int, matchAll bool) string {
+	query := fmt.Sprintf(`id IN (
+		SELECT nt.note_id FROM note_tags nt
+		JOIN tags t ON t.id=nt.tag_id
+		WHERE t.user_id=$1 AND t.name=ANY($%d::varchar[])`, param)
+	if matchAll {
+		query += fmt.Sprintf(`
+		GROUP BY nt.note_id
+		HAVING count(1)=(SELECT count(DISTINCT name) FROM unnest($%d::varchar[]) name)`, param)
+	}
+
+	return query + ")"
+}
+
+// notebookCondition matches the notes of the notebook passed as the
+// parameter with the given number, and of the notebooks inside of it when recursive
+func notebookCondition(param int, recursive bool) string {
+	if !recursive {
+		return fmt.Sprintf("notebook_id=$%d", param)
+	}
+
+	return fmt.Sprintf(`notebook_id IN (
+		WITH RECURSIVE tree AS (
+			SELECT id FROM notebooks WHERE id=$%d AND user_id=$1
+			UNION ALL
+			SELECT nb.id FROM notebooks nb JOIN tree ON nb.parent_id=tree.id
+		)
+		SELECT id FROM tree
+	)`, param)
+}
+
 func (nt *noteRepo) Update(n *repo.Note) (*repo.Note, error) {
 	query := `
 		UPDATE notes SET
-			user_id=$1,
-			title=$2,
-			description=$3,
-			updated_at=$4
-		WHERE id=$5 AND deleted_at IS NULL
-		RETURNING id, user_id, title, description, created_at, updated_at
+			title=$1,
+			description=$2,
+			updated_at=$3,
+			version=version+1
+		WHERE id=$4 AND user_id=$5 AND deleted_at IS NULL
+			AND ($6::integer=0 OR version=$6)
+		RETURNING id, user_id, notebook_id, title, description, created_at, updated_at, version
 	`
 
 	var result repo.Note
 
-	err := nt.db.QueryRow(query,
-		n.UserID,
+	tx, err := nt.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(query,
 		n.Title,
 		n.Description,
 		n.UpdatedAt,
 		n.ID,
+		n.UserID,
+		n.Version,
 	).Scan(
 		&result.ID,
 		&result.UserID,
+		&result.NotebookID,
 		&result.Title,
 		&result.Description,
 		&result.CreatedAt,
 		&result.UpdatedAt,
+		&result.Version,
 	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nt.checkVersion(n.ID, n.UserID, n.Version)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = insertNoteRevision(tx, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	if n.Tags != nil {
+		result.Tags, err = setNoteTags(tx, result.ID, result.UserID, n.Tags)
+	} else {
+		err = tx.Select(&result.Tags, selectNoteTags, result.ID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
 	if err != nil {
 		return nil, err
 	}
@@ -167,21 +340,148 @@ func (nt *noteRepo) Update(n *repo.Note) (*repo.Note, error) {
 	return &result, nil
 }
 
-func (nt *noteRepo) Delete(id int64) error {
+// checkVersion tells why a note wasn't written: ErrVersionMismatch
+// when the note is there with another version, sql.ErrNoRows otherwise
+func (nt *noteRepo) checkVersion(id, userID, version int64) error {
+	if version == 0 {
+		return sql.ErrNoRows
+	}
 
-	query := "DELETE FROM notes WHERE id=$1"
-	result, err := nt.db.Exec(query, id)
+	var exists bool
+	query := "SELECT EXISTS (SELECT 1 FROM notes WHERE id=$1 AND user_id=$2 AND deleted_at IS NULL)"
+	err := nt.db.QueryRow(query, id, userID).Scan(&exists)
 	if err != nil {
 		return err
 	}
 
-	rowsCount, err := result.RowsAffected()
+	if exists {
+		return repo.ErrVersionMismatch
+	}
+
+	return sql.ErrNoRows
+}
+
+// insertNoteRevision stores the content of the note as its next revision.
+// The note has to be written in the same transaction, which keeps its
+// row locked, so that the revisions of the note get unique numbers
+func insertNoteRevision(tx *sqlx.Tx, n *repo.Note) error {
+	query := `
+		INSERT INTO note_revisions(note_id, number, title, description, author_id)
+		SELECT $1, coalesce(max(number), 0) + 1, $2, $3, $4
+		FROM note_revisions WHERE note_id=$1
+	`
+
+	_, err := tx.Exec(query, n.ID, n.Title, n.Description, n.UserID)
+	return err
+}
+
+const selectNoteTags = `
+	SELECT t.name FROM note_tags nt
+	JOIN tags t ON t.id=nt.tag_id
+	WHERE nt.note_id=$1
+	ORDER BY t.name
+`
+
+// noteTagsColumn selects the tag names of every row of the notes table
+// with the given alias as an array
+func noteTagsColumn(alias string) string {
+	return `ARRAY(
+		SELECT t.name FROM note_tags nt
+		JOIN tags t ON t.id=nt.tag_id
+		WHERE nt.note_id=` + alias + `.id
+		ORDER BY t.name
+	)`
+}
+
+// setNoteTags replaces the tags of the note, creating the tags
+// the user doesn't have yet, and returns the tags of the note
+func setNoteTags(tx *sqlx.Tx, noteID, userID int64, tags []string) ([]string, error) {
+	_, err := tx.Exec("DELETE FROM note_tags WHERE note_id=$1", noteID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	if rowsCount == 0 {
-		return sql.ErrNoRows
+	result := make([]string, 0)
+	if len(tags) == 0 {
+		return result, nil
+	}
+
+	query := `
+		INSERT INTO tags(user_id, name)
+		SELECT $1, unnest($2::varchar[])
+		ON CONFLICT (user_id, name) DO NOTHING
+	`
+	_, err = tx.Exec(query, userID, pq.Array(tags))
+	if err != nil {
+		return nil, err
+	}
+
+	query = `
+		INSERT INTO note_tags(note_id, tag_id)
+		SELECT $1, id FROM tags WHERE user_id=$2 AND name=ANY($3::varchar[])
+	`
+	_, err = tx.Exec(query, noteID, userID, pq.Array(tags))
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Select(&result, selectNoteTags, noteID)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (nt *noteRepo) Move(id, userID int64, notebookID *int64) error {
+	query := `
+		UPDATE notes SET notebook_id=$1, version=version+1
+		WHERE id=$2 AND user_id=$3 AND deleted_at IS NULL AND (
+			$1::integer IS NULL OR
+			EXISTS (SELECT 1 FROM notebooks WHERE id=$1 AND user_id=$3)
+		)
+	`
+
+	return execAffectingRows(nt.db, query, notebookID, id, userID)
+}
+
+func (nt *noteRepo) Delete(id, userID, version int64) error {
+	query := `
+		UPDATE notes SET deleted_at=CURRENT_TIMESTAMP, version=version+1
+		WHERE id=$1 AND user_id=$2 AND deleted_at IS NULL
+			AND ($3::integer=0 OR version=$3)
+	`
+
+	err := execAffectingRows(nt.db, query, id, userID, version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nt.checkVersion(id, userID, version)
+	}
+
+	return err
+}
+
+func (nt *noteRepo) Restore(id, userID int64) error {
+	query := `
+		UPDATE notes SET deleted_at=NULL, version=version+1
+		WHERE id=$1 AND user_id=$2 AND deleted_at IS NOT NULL
+	`
+
+	return execAffectingRows(nt.db, query, id, userID)
+}
+
+func (nt *noteRepo) DeletePermanently(id, userID int64) error {
+	query := "DELETE FROM notes WHERE id=$1 AND user_id=$2 AND deleted_at IS NOT NULL"
+
+	return execAffectingRows(nt.db, query, id, userID)
+}
+
+func (nt *noteRepo) PurgeTrash(before time.Time) (int64, error) {
+	query := "DELETE FROM notes WHERE deleted_at < $1"
+
+	result, err := nt.db.Exec(query, before)
+	if err != nil {
+		return 0, err
 	}
-	return nil
+
+	return result.RowsAffected()
 }
@@ -0,0 +1,103 @@
+package postgres
+
+import (
+	"github.com/jmoiron/sqlx"
+	"github.com/mirasildev/note_project/storage/repo"
+)
+
+type noteRevisionRepo struct {
+	db *sqlx.DB
+}
+
+func NewNoteRevision(db *sqlx.DB) repo.NoteRevisionStorageI {
+	return &noteRevisionRepo{
+		db: db,
+	}
+}
+
+const selectNoteRevisions = `
+	SELECT
+		r.id,
+		r.note_id,
+		r.number,
+		r.title,
+		r.description,
+		r.author_id,
+		r.created_at
+	FROM note_revisions r
+	JOIN notes n ON n.id=r.note_id
+	WHERE r.note_id=$1 AND n.user_id=$2 AND n.deleted_at IS NULL
+`
+
+func scanNoteRevision(row interface{ Scan(...interface{}) error }) (*repo.NoteRevision, error) {
+	var result repo.NoteRevision
+
+	err := row.Scan(
+		&result.ID,
+		&result.NoteID,
+		&result.Number,
+		&result.Title,
+		&result.Description,
+		&result.AuthorID,
+		&result.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (rr *noteRevisionRepo) GetAll(noteID, userID int64) ([]*repo.NoteRevision, error) {
+	rows, err := rr.db.Query(selectNoteRevisions+" ORDER BY r.number desc", noteID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]*repo.NoteRevision, 0)
+	for rows.Next() {
+		r, err := scanNoteRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, r)
+	}
+
+	return result, rows.Err()
+}
+
+func (rr *noteRevisionRepo) Get(noteID, userID int64, number int) (*repo.NoteRevision, error) {
+	return scanNoteRevision(rr.db.QueryRow(selectNoteRevisions+" AND r.number=$3", noteID, userID, number))
+}
+
+func (rr *noteRevisionRepo) Prune(noteID int64, keep int) error {
+	query := `
+		DELETE FROM note_revisions WHERE note_id=$1 AND number <= (
+			SELECT max(number) - $2 FROM note_revisions WHERE note_id=$1
+		)
+	`
+
+	_, err := rr.db.Exec(query, noteID, keep)
+	return err
+}
+
+func (rr *noteRevisionRepo) PruneAll(userID int64, keep int) error {
+	query := `
+		DELETE FROM note_revisions WHERE id IN (
+			SELECT id FROM (
+				SELECT
+					r.id,
+					row_number() OVER (PARTITION BY r.note_id ORDER BY r.number desc) AS position
+				FROM note_revisions r
+				JOIN notes n ON n.id=r.note_id
+				WHERE n.user_id=$1
+			) ranked
+			WHERE position > $2
+		)
+	`
+
+	_, err := rr.db.Exec(query, userID, keep)
+	return err
+}
@@ -0,0 +1,58 @@
+package postgres_test
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/go-faker/faker/v4"
+	"github.com/mirasildev/note_project/storage/repo"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNoteRevisions(t *testing.T) {
+	n := createNote(t)
+
+	for i := 0; i < 3; i++ {
+		_, err := strg.Note().Update(&repo.Note{
+			ID:          n.ID,
+			UserID:      n.UserID,
+			Title:       faker.Sentence(),
+			Description: faker.Sentence(),
+			UpdatedAt:   time.Now(),
+		})
+		require.NoError(t, err)
+	}
+
+	revisions, err := strg.NoteRevision().GetAll(n.ID, n.UserID)
+	require.NoError(t, err)
+	require.Len(t, revisions, 4)
+	require.Equal(t, 4, revisions[0].Number)
+	require.Equal(t, n.UserID, *revisions[0].AuthorID)
+
+	first, err := strg.NoteRevision().Get(n.ID, n.UserID, 1)
+	require.NoError(t, err)
+	require.Equal(t, n.Title, first.Title)
+
+	other := createUser(t)
+	_, err = strg.NoteRevision().Get(n.ID, other.ID, 1)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	err = strg.NoteRevision().Prune(n.ID, 2)
+	require.NoError(t, err)
+
+	revisions, err = strg.NoteRevision().GetAll(n.ID, n.UserID)
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+	require.Equal(t, 3, revisions[1].Number)
+
+	err = strg.NoteRevision().PruneAll(n.UserID, 1)
+	require.NoError(t, err)
+
+	revisions, err = strg.NoteRevision().GetAll(n.ID, n.UserID)
+	require.NoError(t, err)
+	require.Len(t, revisions, 1)
+
+	deleteUser(other.ID, t)
+	deleteUser(n.UserID, t)
+}
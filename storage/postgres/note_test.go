@@ -1,6 +1,7 @@
 package postgres_test
 
 import (
+	"database/sql"
 	"testing"
 	"time"
 
@@ -23,15 +24,16 @@ func createNote(t *testing.T) *repo.Note {
 	return user
 }
 
-func deleteNote(id int64, t *testing.T) {
-	err := strg.Note().Delete(id)
+func deleteNote(id, userID int64, t *testing.T) {
+	err := strg.Note().Delete(id, userID, 0)
 	require.NoError(t, err)
 }
 
 func updateNote(t *testing.T) {
-	u := createUser(t)
+	n := createNote(t)
 	note, err := strg.Note().Update(&repo.Note{
-		UserID: u.ID,
+		ID: n.ID,
+		UserID: n.UserID,
 		Title: faker.Sentence(),
 		Description: faker.Sentence(),
 		UpdatedAt: time.Now(),
@@ -39,29 +41,42 @@ func updateNote(t *testing.T) {
 	require.NoError(t, err)
 	require.NotEmpty(t, note)
 
-	deleteNote(note.ID, t)
+	deleteNote(note.ID, note.UserID, t)
 }
 func TestCreateNote(t *testing.T) {
 	note := createNote(t)
-	deleteUser(note.ID, t)
+	deleteUser(note.UserID, t)
 	require.NotEmpty(t, note)
 }
 
 func TestGetNote(t *testing.T) {
 	c := createNote(t)
 
-	Note, err := strg.Note().Get(c.ID)
+	Note, err := strg.Note().Get(c.ID, c.UserID)
 	require.NoError(t, err)
 	require.NotEmpty(t, Note)
 }
 
+func TestGetNoteOfAnotherUser(t *testing.T) {
+	c := createNote(t)
+	u := createUser(t)
+
+	_, err := strg.Note().Get(c.ID, u.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	err = strg.Note().Delete(c.ID, u.ID, 0)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
 func TestUpdateNote(t *testing.T) {
 	updateNote(t)
 }
 
 func TestGetAllNote(t *testing.T) {
+	c := createNote(t)
 
 	Notes, err := strg.Note().GetAllNotes(&repo.GetAllNotesParams{
+		UserID: c.UserID,
 		Limit: 10,
 		Page:  1,
 	})
@@ -69,3 +84,159 @@ func TestGetAllNote(t *testing.T) {
 	require.NotEmpty(t, Notes)
 
 }
+
+func TestNoteTrash(t *testing.T) {
+	n := createNote(t)
+
+	deleteNote(n.ID, n.UserID, t)
+
+	_, err := strg.Note().Get(n.ID, n.UserID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	trash, err := strg.Note().GetTrash(&repo.GetAllNotesParams{
+		UserID: n.UserID,
+		Limit:  10,
+		Page:   1,
+	})
+	require.NoError(t, err)
+	require.Len(t, trash.Notes, 1)
+	require.NotNil(t, trash.Notes[0].DeletedAt)
+
+	err = strg.Note().Restore(n.ID, n.UserID)
+	require.NoError(t, err)
+
+	_, err = strg.Note().Get(n.ID, n.UserID)
+	require.NoError(t, err)
+
+	err = strg.Note().DeletePermanently(n.ID, n.UserID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	deleteNote(n.ID, n.UserID, t)
+
+	err = strg.Note().DeletePermanently(n.ID, n.UserID)
+	require.NoError(t, err)
+
+	deleteUser(n.UserID, t)
+}
+
+func TestPurgeTrash(t *testing.T) {
+	n := createNote(t)
+	deleteNote(n.ID, n.UserID, t)
+
+	// the note was trashed after the cutoff, so it is kept
+	_, err := strg.Note().PurgeTrash(time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+
+	err = strg.Note().Restore(n.ID, n.UserID)
+	require.NoError(t, err)
+	deleteNote(n.ID, n.UserID, t)
+
+	count, err := strg.Note().PurgeTrash(time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, count, int64(1))
+
+	err = strg.Note().Restore(n.ID, n.UserID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	deleteUser(n.UserID, t)
+}
+
+func TestSearchNotes(t *testing.T) {
+	u := createUser(t)
+
+	create := func(title, description string) *repo.Note {
+		n, err := strg.Note().Create(&repo.Note{
+			UserID:      u.ID,
+			Title:       title,
+			Description: description,
+			CreatedAt:   time.Now(),
+		})
+		require.NoError(t, err)
+		return n
+	}
+
+	recipe := create("Pancake recipe", "Mix the flour with milk and eggs")
+	create("Shopping list", "Buy flour and sugar")
+
+	search := func(query string) *repo.GetAllNotesResult {
+		result, err := strg.Note().GetAllNotes(&repo.GetAllNotesParams{
+			UserID: u.ID,
+			Limit:  10,
+			Page:   1,
+			Search: query,
+		})
+		require.NoError(t, err)
+		return result
+	}
+
+	result := search("flour")
+	require.Equal(t, int32(2), result.Count)
+
+	result = search("pancake")
+	require.Len(t, result.Notes, 1)
+	require.Equal(t, recipe.ID, result.Notes[0].ID)
+	require.NotNil(t, result.Notes[0].Highlight)
+	require.Contains(t, result.Notes[0].Highlight.Title, "<mark>Pancake</mark>")
+
+	result = search(`"flour with milk"`)
+	require.Len(t, result.Notes, 1)
+	require.Equal(t, recipe.ID, result.Notes[0].ID)
+
+	result = search("flour -sugar")
+	require.Len(t, result.Notes, 1)
+	require.Equal(t, recipe.ID, result.Notes[0].ID)
+
+	result = search("")
+	require.Len(t, result.Notes, 2)
+	require.Nil(t, result.Notes[0].Highlight)
+
+	create("Waffle <script>alert(1)</script>", "Waffle \x02marked\x03 by hand")
+
+	result = search("waffle")
+	require.Len(t, result.Notes, 1)
+	require.NotNil(t, result.Notes[0].Highlight)
+	require.Contains(t, result.Notes[0].Highlight.Title, "<mark>Waffle</mark> &lt;script&gt;")
+	require.NotContains(t, result.Notes[0].Highlight.Title, "<script>")
+	require.NotContains(t, result.Notes[0].Highlight.Description, "<mark>marked</mark>")
+
+	deleteUser(u.ID, t)
+}
+
+func TestNoteVersion(t *testing.T) {
+	n := createNote(t)
+	require.Equal(t, int64(1), n.Version)
+
+	update := func(version int64) (*repo.Note, error) {
+		return strg.Note().Update(&repo.Note{
+			ID:          n.ID,
+			UserID:      n.UserID,
+			Title:       faker.Sentence(),
+			Description: faker.Sentence(),
+			UpdatedAt:   time.Now(),
+			Version:     version,
+		})
+	}
+
+	updated, err := update(1)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), updated.Version)
+
+	_, err = update(1)
+	require.ErrorIs(t, err, repo.ErrVersionMismatch)
+
+	// without a version the note is overwritten
+	updated, err = update(0)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), updated.Version)
+
+	err = strg.Note().Delete(n.ID, n.UserID, 2)
+	require.ErrorIs(t, err, repo.ErrVersionMismatch)
+
+	err = strg.Note().Delete(n.ID, n.UserID, 3)
+	require.NoError(t, err)
+
+	_, err = update(4)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	deleteUser(n.UserID, t)
+}
@@ -0,0 +1,181 @@
+package postgres
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/mirasildev/note_project/storage/repo"
+)
+
+type notebookRepo struct {
+	db *sqlx.DB
+}
+
+func NewNotebook(db *sqlx.DB) repo.NotebookStorageI {
+	return &notebookRepo{
+		db: db,
+	}
+}
+
+const selectNotebooks = `
+	SELECT
+		nb.id,
+		nb.user_id,
+		nb.parent_id,
+		nb.name,
+		nb.created_at,
+		nb.updated_at,
+		count(n.id)
+	FROM notebooks nb
+	LEFT JOIN notes n ON n.notebook_id=nb.id AND n.deleted_at IS NULL
+`
+
+func scanNotebook(row interface{ Scan(...interface{}) error }) (*repo.Notebook, error) {
+	var result repo.Notebook
+
+	err := row.Scan(
+		&result.ID,
+		&result.UserID,
+		&result.ParentID,
+		&result.Name,
+		&result.CreatedAt,
+		&result.UpdatedAt,
+		&result.NoteCount,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (nr *notebookRepo) Create(n *repo.Notebook) (*repo.Notebook, error) {
+	// the parent has to be a notebook of the same user
+	query := `
+		INSERT INTO notebooks(user_id, parent_id, name)
+		SELECT $1, $2, $3
+		WHERE $2::integer IS NULL OR EXISTS (
+			SELECT 1 FROM notebooks WHERE id=$2 AND user_id=$1
+		)
+		RETURNING id, created_at
+	`
+
+	err := nr.db.QueryRow(query, n.UserID, n.ParentID, n.Name).Scan(&n.ID, &n.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return n, nil
+}
+
+func (nr *notebookRepo) Get(id, userID int64) (*repo.Notebook, error) {
+	query := selectNotebooks + " WHERE nb.id=$1 AND nb.user_id=$2 GROUP BY nb.id"
+
+	return scanNotebook(nr.db.QueryRow(query, id, userID))
+}
+
+func (nr *notebookRepo) GetAll(userID int64) ([]*repo.Notebook, error) {
+	query := selectNotebooks + " WHERE nb.user_id=$1 GROUP BY nb.id ORDER BY nb.name, nb.id"
+
+	rows, err := nr.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]*repo.Notebook, 0)
+	for rows.Next() {
+		n, err := scanNotebook(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, n)
+	}
+
+	return result, rows.Err()
+}
+
+func (nr *notebookRepo) Rename(id, userID int64, name string) (*repo.Notebook, error) {
+	query := "UPDATE notebooks SET name=$1, updated_at=$2 WHERE id=$3 AND user_id=$4"
+
+	err := execAffectingRows(nr.db, query, name, time.Now(), id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return nr.Get(id, userID)
+}
+
+func (nr *notebookRepo) Move(id, userID int64, parentID *int64) (*repo.Notebook, error) {
+	tx, err := nr.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// lock the notebooks of the user, so that two moves
+	// running at the same time can't make a cycle together
+	_, err = tx.Exec("SELECT id FROM notebooks WHERE user_id=$1 FOR UPDATE", userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if parentID != nil {
+		// walk up from the new parent, the notebook must not be on the way
+		query := `
+			WITH RECURSIVE ancestors AS (
+				SELECT id, parent_id FROM notebooks WHERE id=$1 AND user_id=$2
+				UNION ALL
+				SELECT nb.id, nb.parent_id FROM notebooks nb
+				JOIN ancestors a ON nb.id=a.parent_id
+			)
+			SELECT
+				EXISTS (SELECT 1 FROM ancestors),
+				EXISTS (SELECT 1 FROM ancestors WHERE id=$3)
+		`
+
+		var parentExists, cycle bool
+		err = tx.QueryRow(query, *parentID, userID, id).Scan(&parentExists, &cycle)
+		if err != nil {
+			return nil, err
+		}
+
+		if !parentExists {
+			return nil, sql.ErrNoRows
+		}
+
+		if cycle {
+			return nil, repo.ErrNotebookCycle
+		}
+	}
+
+	query := "UPDATE notebooks SET parent_id=$1, updated_at=$2 WHERE id=$3 AND user_id=$4"
+	result, err := tx.Exec(query, parentID, time.Now(), id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	rowsCount, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	if rowsCount == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return nr.Get(id, userID)
+}
+
+func (nr *notebookRepo) Delete(id, userID int64) error {
+	query := "DELETE FROM notebooks WHERE id=$1 AND user_id=$2"
+
+	return execAffectingRows(nr.db, query, id, userID)
+}
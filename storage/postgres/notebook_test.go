@@ -0,0 +1,117 @@
+package postgres_test
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/go-faker/faker/v4"
+	"github.com/mirasildev/note_project/storage/repo"
+	"github.com/stretchr/testify/require"
+)
+
+func createNotebook(t *testing.T, userID int64, parentID *int64) *repo.Notebook {
+	n, err := strg.Notebook().Create(&repo.Notebook{
+		UserID:   userID,
+		ParentID: parentID,
+		Name:     faker.Word(),
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, n)
+
+	return n
+}
+
+func TestNotebooks(t *testing.T) {
+	u := createUser(t)
+
+	root := createNotebook(t, u.ID, nil)
+	child := createNotebook(t, u.ID, &root.ID)
+	grandchild := createNotebook(t, u.ID, &child.ID)
+
+	other := createUser(t)
+	_, err := strg.Notebook().Create(&repo.Notebook{
+		UserID:   other.ID,
+		ParentID: &root.ID,
+		Name:     faker.Word(),
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	_, err = strg.Notebook().Move(root.ID, u.ID, &grandchild.ID)
+	require.ErrorIs(t, err, repo.ErrNotebookCycle)
+
+	_, err = strg.Notebook().Move(root.ID, u.ID, &root.ID)
+	require.ErrorIs(t, err, repo.ErrNotebookCycle)
+
+	moved, err := strg.Notebook().Move(grandchild.ID, u.ID, nil)
+	require.NoError(t, err)
+	require.Nil(t, moved.ParentID)
+
+	renamed, err := strg.Notebook().Rename(child.ID, u.ID, "renamed")
+	require.NoError(t, err)
+	require.Equal(t, "renamed", renamed.Name)
+
+	notebooks, err := strg.Notebook().GetAll(u.ID)
+	require.NoError(t, err)
+	require.Len(t, notebooks, 3)
+
+	err = strg.Notebook().Delete(root.ID, u.ID)
+	require.NoError(t, err)
+
+	_, err = strg.Notebook().Get(child.ID, u.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	deleteUser(other.ID, t)
+	deleteUser(u.ID, t)
+}
+
+func TestNotesInNotebooks(t *testing.T) {
+	u := createUser(t)
+
+	root := createNotebook(t, u.ID, nil)
+	child := createNotebook(t, u.ID, &root.ID)
+
+	inRoot, err := strg.Note().Create(&repo.Note{
+		UserID:      u.ID,
+		NotebookID:  &root.ID,
+		Title:       faker.Sentence(),
+		Description: faker.Sentence(),
+		CreatedAt:   time.Now(),
+	})
+	require.NoError(t, err)
+
+	inChild := createTaggedNote(t, u.ID)
+	err = strg.Note().Move(inChild.ID, u.ID, &child.ID)
+	require.NoError(t, err)
+
+	filter := func(recursive bool) *repo.GetAllNotesResult {
+		result, err := strg.Note().GetAllNotes(&repo.GetAllNotesParams{
+			UserID:     u.ID,
+			Limit:      10,
+			Page:       1,
+			NotebookID: &root.ID,
+			Recursive:  recursive,
+		})
+		require.NoError(t, err)
+		return result
+	}
+
+	result := filter(false)
+	require.Len(t, result.Notes, 1)
+	require.Equal(t, inRoot.ID, result.Notes[0].ID)
+	require.Equal(t, int32(2), filter(true).Count)
+
+	notebook, err := strg.Notebook().Get(root.ID, u.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), notebook.NoteCount)
+
+	// the notes are kept when the notebook is deleted
+	err = strg.Notebook().Delete(root.ID, u.ID)
+	require.NoError(t, err)
+
+	note, err := strg.Note().Get(inChild.ID, u.ID)
+	require.NoError(t, err)
+	require.Nil(t, note.NotebookID)
+
+	deleteUser(u.ID, t)
+}
@@ -0,0 +1,129 @@
+package postgres
+
+import (
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/mirasildev/note_project/storage/repo"
+)
+
+type oauthClientRepo struct {
+	db *sqlx.DB
+}
+
+func NewOAuthClient(db *sqlx.DB) repo.OAuthClientStorageI {
+	return &oauthClientRepo{
+		db: db,
+	}
+}
+
+func (or *oauthClientRepo) Create(c *repo.OAuthClient) (*repo.OAuthClient, error) {
+	query := `
+		INSERT INTO oauth_clients(
+			id,
+			user_id,
+			name,
+			secret_hash,
+			redirect_uris,
+			scopes
+		) VALUES($1, $2, $3, $4, $5, $6)
+		RETURNING created_at
+	`
+
+	err := or.db.QueryRow(
+		query,
+		c.ID,
+		c.UserID,
+		c.Name,
+		c.SecretHash,
+		pq.Array(c.RedirectURIs),
+		pq.Array(c.Scopes),
+	).Scan(&c.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (or *oauthClientRepo) Get(id string) (*repo.OAuthClient, error) {
+	var result repo.OAuthClient
+
+	query := `
+		SELECT
+			id,
+			user_id,
+			name,
+			secret_hash,
+			redirect_uris,
+			scopes,
+			created_at
+		FROM oauth_clients
+		WHERE id=$1
+	`
+
+	err := or.db.QueryRow(query, id).Scan(
+		&result.ID,
+		&result.UserID,
+		&result.Name,
+		&result.SecretHash,
+		pq.Array(&result.RedirectURIs),
+		pq.Array(&result.Scopes),
+		&result.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (or *oauthClientRepo) GetAll(userID int64) ([]*repo.OAuthClient, error) {
+	result := make([]*repo.OAuthClient, 0)
+
+	query := `
+		SELECT
+			id,
+			user_id,
+			name,
+			secret_hash,
+			redirect_uris,
+			scopes,
+			created_at
+		FROM oauth_clients
+		WHERE user_id=$1
+		ORDER BY created_at desc
+	`
+
+	rows, err := or.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var c repo.OAuthClient
+
+		err := rows.Scan(
+			&c.ID,
+			&c.UserID,
+			&c.Name,
+			&c.SecretHash,
+			pq.Array(&c.RedirectURIs),
+			pq.Array(&c.Scopes),
+			&c.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, &c)
+	}
+
+	return result, nil
+}
+
+func (or *oauthClientRepo) Delete(id string, userID int64) error {
+	query := "DELETE FROM oauth_clients WHERE id=$1 AND user_id=$2"
+	return execAffectingRows(or.db, query, id, userID)
+}
@@ -0,0 +1,40 @@
+package postgres_test
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/go-faker/faker/v4"
+	"github.com/mirasildev/note_project/storage/repo"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOAuthClient(t *testing.T) {
+	u := createUser(t)
+	defer deleteUser(u.ID, t)
+
+	client, err := strg.OAuthClient().Create(&repo.OAuthClient{
+		ID:           faker.UUIDDigit(),
+		UserID:       u.ID,
+		Name:         faker.Word(),
+		RedirectURIs: []string{"http://127.0.0.1/callback"},
+		Scopes:       []string{"notes:read"},
+	})
+	require.NoError(t, err)
+
+	found, err := strg.OAuthClient().Get(client.ID)
+	require.NoError(t, err)
+	require.Nil(t, found.SecretHash)
+	require.Equal(t, client.RedirectURIs, found.RedirectURIs)
+	require.Equal(t, client.Scopes, found.Scopes)
+
+	clients, err := strg.OAuthClient().GetAll(u.ID)
+	require.NoError(t, err)
+	require.Len(t, clients, 1)
+
+	err = strg.OAuthClient().Delete(client.ID, u.ID)
+	require.NoError(t, err)
+
+	_, err = strg.OAuthClient().Get(client.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
@@ -0,0 +1,133 @@
+package postgres
+
+import (
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/mirasildev/note_project/storage/repo"
+)
+
+type passkeyRepo struct {
+	db *sqlx.DB
+}
+
+func NewPasskey(db *sqlx.DB) repo.PasskeyStorageI {
+	return &passkeyRepo{
+		db: db,
+	}
+}
+
+func (pr *passkeyRepo) Create(p *repo.Passkey) (*repo.Passkey, error) {
+	query := `
+		INSERT INTO passkeys(
+			id,
+			user_id,
+			name,
+			public_key,
+			sign_count
+		) VALUES($1, $2, $3, $4, $5)
+		RETURNING created_at
+	`
+
+	err := pr.db.QueryRow(
+		query,
+		p.ID,
+		p.UserID,
+		p.Name,
+		p.PublicKey,
+		p.SignCount,
+	).Scan(&p.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (pr *passkeyRepo) Get(id string) (*repo.Passkey, error) {
+	var result repo.Passkey
+
+	query := `
+		SELECT
+			id,
+			user_id,
+			name,
+			public_key,
+			sign_count,
+			last_used_at,
+			created_at
+		FROM passkeys
+		WHERE id=$1
+	`
+
+	err := pr.db.QueryRow(query, id).Scan(
+		&result.ID,
+		&result.UserID,
+		&result.Name,
+		&result.PublicKey,
+		&result.SignCount,
+		&result.LastUsedAt,
+		&result.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (pr *passkeyRepo) GetAll(userID int64) ([]*repo.Passkey, error) {
+	result := make([]*repo.Passkey, 0)
+
+	query := `
+		SELECT
+			id,
+			user_id,
+			name,
+			public_key,
+			sign_count,
+			last_used_at,
+			created_at
+		FROM passkeys
+		WHERE user_id=$1
+		ORDER BY created_at desc
+	`
+
+	rows, err := pr.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var p repo.Passkey
+
+		err := rows.Scan(
+			&p.ID,
+			&p.UserID,
+			&p.Name,
+			&p.PublicKey,
+			&p.SignCount,
+			&p.LastUsedAt,
+			&p.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, &p)
+	}
+
+	return result, nil
+}
+
+func (pr *passkeyRepo) UpdateSignCount(id string, signCount uint32, lastUsedAt time.Time) error {
+	query := "UPDATE passkeys SET sign_count=$1, last_used_at=$2 WHERE id=$3"
+	return execAffectingRows(pr.db, query, signCount, lastUsedAt, id)
+}
+
+func (pr *passkeyRepo) Delete(id string, userID int64) error {
+	query := "DELETE FROM passkeys WHERE id=$1 AND user_id=$2"
+	return execAffectingRows(pr.db, query, id, userID)
+}
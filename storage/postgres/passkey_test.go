@@ -0,0 +1,46 @@
+package postgres_test
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/go-faker/faker/v4"
+	"github.com/mirasildev/note_project/storage/repo"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPasskey(t *testing.T) {
+	u := createUser(t)
+	defer deleteUser(u.ID, t)
+
+	passkey, err := strg.Passkey().Create(&repo.Passkey{
+		ID:        faker.UUIDDigit(),
+		UserID:    u.ID,
+		Name:      faker.Word(),
+		PublicKey: []byte{1, 2, 3},
+	})
+	require.NoError(t, err)
+
+	err = strg.Passkey().UpdateSignCount(passkey.ID, 7, time.Now())
+	require.NoError(t, err)
+
+	found, err := strg.Passkey().Get(passkey.ID)
+	require.NoError(t, err)
+	require.Equal(t, []byte{1, 2, 3}, found.PublicKey)
+	require.Equal(t, uint32(7), found.SignCount)
+	require.NotNil(t, found.LastUsedAt)
+
+	passkeys, err := strg.Passkey().GetAll(u.ID)
+	require.NoError(t, err)
+	require.Len(t, passkeys, 1)
+
+	err = strg.Passkey().Delete(passkey.ID, u.ID+1)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	err = strg.Passkey().Delete(passkey.ID, u.ID)
+	require.NoError(t, err)
+
+	_, err = strg.Passkey().Get(passkey.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
@@ -0,0 +1,145 @@
+package postgres
+
+import (
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/mirasildev/note_project/storage/repo"
+)
+
+type personalAccessTokenRepo struct {
+	db *sqlx.DB
+}
+
+func NewPersonalAccessToken(db *sqlx.DB) repo.PersonalAccessTokenStorageI {
+	return &personalAccessTokenRepo{
+		db: db,
+	}
+}
+
+func (pr *personalAccessTokenRepo) Create(t *repo.PersonalAccessToken) (*repo.PersonalAccessToken, error) {
+	query := `
+		INSERT INTO personal_access_tokens(
+			id,
+			user_id,
+			name,
+			token_hash,
+			scopes,
+			expires_at
+		) VALUES($1, $2, $3, $4, $5, $6)
+		RETURNING created_at
+	`
+
+	err := pr.db.QueryRow(
+		query,
+		t.ID,
+		t.UserID,
+		t.Name,
+		t.TokenHash,
+		pq.Array(t.Scopes),
+		t.ExpiresAt,
+	).Scan(&t.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func (pr *personalAccessTokenRepo) GetByHash(tokenHash string) (*repo.PersonalAccessToken, error) {
+	var result repo.PersonalAccessToken
+
+	query := `
+		SELECT
+			id,
+			user_id,
+			name,
+			token_hash,
+			scopes,
+			expires_at,
+			last_used_at,
+			created_at
+		FROM personal_access_tokens
+		WHERE token_hash=$1
+	`
+
+	err := pr.db.QueryRow(query, tokenHash).Scan(
+		&result.ID,
+		&result.UserID,
+		&result.Name,
+		&result.TokenHash,
+		pq.Array(&result.Scopes),
+		&result.ExpiresAt,
+		&result.LastUsedAt,
+		&result.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (pr *personalAccessTokenRepo) GetAll(userID int64) ([]*repo.PersonalAccessToken, error) {
+	result := make([]*repo.PersonalAccessToken, 0)
+
+	query := `
+		SELECT
+			id,
+			user_id,
+			name,
+			token_hash,
+			scopes,
+			expires_at,
+			last_used_at,
+			created_at
+		FROM personal_access_tokens
+		WHERE user_id=$1
+		ORDER BY created_at desc
+	`
+
+	rows, err := pr.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var t repo.PersonalAccessToken
+
+		err := rows.Scan(
+			&t.ID,
+			&t.UserID,
+			&t.Name,
+			&t.TokenHash,
+			pq.Array(&t.Scopes),
+			&t.ExpiresAt,
+			&t.LastUsedAt,
+			&t.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, &t)
+	}
+
+	return result, nil
+}
+
+func (pr *personalAccessTokenRepo) UpdateLastUsed(id string, lastUsedAt time.Time) error {
+	query := "UPDATE personal_access_tokens SET last_used_at=$1 WHERE id=$2"
+	return execAffectingRows(pr.db, query, lastUsedAt, id)
+}
+
+func (pr *personalAccessTokenRepo) Delete(id string, userID int64) error {
+	query := "DELETE FROM personal_access_tokens WHERE id=$1 AND user_id=$2"
+	return execAffectingRows(pr.db, query, id, userID)
+}
+
+func (pr *personalAccessTokenRepo) DeleteAll(userID int64) error {
+	_, err := pr.db.Exec("DELETE FROM personal_access_tokens WHERE user_id=$1", userID)
+	return err
+}
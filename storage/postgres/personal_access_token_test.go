@@ -0,0 +1,40 @@
+package postgres_test
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/go-faker/faker/v4"
+	"github.com/google/uuid"
+	"github.com/mirasildev/note_project/storage/repo"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPersonalAccessToken(t *testing.T) {
+	u := createUser(t)
+	defer deleteUser(u.ID, t)
+
+	token, err := strg.PersonalAccessToken().Create(&repo.PersonalAccessToken{
+		ID:        uuid.NewString(),
+		UserID:    u.ID,
+		Name:      faker.Word(),
+		TokenHash: faker.UUIDDigit(),
+		Scopes:    []string{"notes:read"},
+	})
+	require.NoError(t, err)
+
+	found, err := strg.PersonalAccessToken().GetByHash(token.TokenHash)
+	require.NoError(t, err)
+	require.Equal(t, token.ID, found.ID)
+	require.Equal(t, []string{"notes:read"}, found.Scopes)
+
+	tokens, err := strg.PersonalAccessToken().GetAll(u.ID)
+	require.NoError(t, err)
+	require.Len(t, tokens, 1)
+
+	err = strg.PersonalAccessToken().Delete(token.ID, u.ID)
+	require.NoError(t, err)
+
+	_, err = strg.PersonalAccessToken().GetByHash(token.TokenHash)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
@@ -0,0 +1,156 @@
+package postgres
+
+import (
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/mirasildev/note_project/storage/repo"
+)
+
+type sessionRepo struct {
+	db *sqlx.DB
+}
+
+func NewSession(db *sqlx.DB) repo.SessionStorageI {
+	return &sessionRepo{
+		db: db,
+	}
+}
+
+func (sr *sessionRepo) Create(s *repo.Session) (*repo.Session, error) {
+	query := `
+		INSERT INTO sessions(
+			id,
+			user_id,
+			client_id,
+			user_agent,
+			ip_address,
+			expires_at
+		) VALUES($1, $2, $3, $4, $5, $6)
+		RETURNING created_at, last_seen_at
+	`
+
+	err := sr.db.QueryRow(
+		query,
+		s.ID,
+		s.UserID,
+		s.ClientID,
+		s.UserAgent,
+		s.IPAddress,
+		s.ExpiresAt,
+	).Scan(&s.CreatedAt, &s.LastSeenAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (sr *sessionRepo) Get(id string) (*repo.Session, error) {
+	var result repo.Session
+
+	query := `
+		SELECT
+			id,
+			user_id,
+			client_id,
+			user_agent,
+			ip_address,
+			created_at,
+			last_seen_at,
+			expires_at,
+			revoked_at
+		FROM sessions
+		WHERE id=$1
+	`
+
+	err := sr.db.QueryRow(query, id).Scan(
+		&result.ID,
+		&result.UserID,
+		&result.ClientID,
+		&result.UserAgent,
+		&result.IPAddress,
+		&result.CreatedAt,
+		&result.LastSeenAt,
+		&result.ExpiresAt,
+		&result.RevokedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (sr *sessionRepo) GetAll(userID int64) ([]*repo.Session, error) {
+	return sr.getAll("user_id=$1", userID)
+}
+
+func (sr *sessionRepo) GetAllByClient(clientID string) ([]*repo.Session, error) {
+	return sr.getAll("client_id=$1", clientID)
+}
+
+func (sr *sessionRepo) getAll(filter string, args ...interface{}) ([]*repo.Session, error) {
+	result := make([]*repo.Session, 0)
+
+	query := `
+		SELECT
+			id,
+			user_id,
+			client_id,
+			user_agent,
+			ip_address,
+			created_at,
+			last_seen_at,
+			expires_at,
+			revoked_at
+		FROM sessions
+		WHERE ` + filter + ` AND revoked_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+		ORDER BY last_seen_at desc
+	`
+
+	rows, err := sr.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var s repo.Session
+
+		err := rows.Scan(
+			&s.ID,
+			&s.UserID,
+			&s.ClientID,
+			&s.UserAgent,
+			&s.IPAddress,
+			&s.CreatedAt,
+			&s.LastSeenAt,
+			&s.ExpiresAt,
+			&s.RevokedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, &s)
+	}
+
+	return result, nil
+}
+
+func (sr *sessionRepo) UpdateLastSeen(id string, lastSeenAt time.Time) error {
+	query := "UPDATE sessions SET last_seen_at=$1 WHERE id=$2"
+	return execAffectingRows(sr.db, query, lastSeenAt, id)
+}
+
+func (sr *sessionRepo) Revoke(id string) error {
+	_, err := sr.db.Exec("UPDATE sessions SET revoked_at=CURRENT_TIMESTAMP WHERE id=$1 AND revoked_at IS NULL", id)
+	return err
+}
+
+func (sr *sessionRepo) RevokeAll(userID int64) error {
+	_, err := sr.db.Exec("UPDATE sessions SET revoked_at=CURRENT_TIMESTAMP WHERE user_id=$1 AND revoked_at IS NULL", userID)
+	return err
+}
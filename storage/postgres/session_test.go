@@ -0,0 +1,82 @@
+package postgres_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mirasildev/note_project/storage/repo"
+	"github.com/stretchr/testify/require"
+)
+
+func createSession(t *testing.T, userID int64) *repo.Session {
+	session, err := strg.Session().Create(&repo.Session{
+		ID:        uuid.NewString(),
+		UserID:    userID,
+		UserAgent: "Mozilla/5.0",
+		IPAddress: "127.0.0.1",
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	return session
+}
+
+func TestSession(t *testing.T) {
+	u := createUser(t)
+	defer deleteUser(u.ID, t)
+
+	first := createSession(t, u.ID)
+	second := createSession(t, u.ID)
+
+	lastSeen := time.Now().Add(24 * time.Hour)
+	err := strg.Session().UpdateLastSeen(first.ID, lastSeen)
+	require.NoError(t, err)
+
+	sessions, err := strg.Session().GetAll(u.ID)
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+	require.Equal(t, first.ID, sessions[0].ID)
+
+	err = strg.Session().Revoke(second.ID)
+	require.NoError(t, err)
+
+	revoked, err := strg.Session().Get(second.ID)
+	require.NoError(t, err)
+	require.NotNil(t, revoked.RevokedAt)
+
+	sessions, err = strg.Session().GetAll(u.ID)
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+
+	err = strg.Session().RevokeAll(u.ID)
+	require.NoError(t, err)
+
+	sessions, err = strg.Session().GetAll(u.ID)
+	require.NoError(t, err)
+	require.Empty(t, sessions)
+}
+
+func TestGetSessionsByClient(t *testing.T) {
+	u := createUser(t)
+	defer deleteUser(u.ID, t)
+
+	clientID := uuid.NewString()
+
+	createSession(t, u.ID)
+	session, err := strg.Session().Create(&repo.Session{
+		ID:        uuid.NewString(),
+		UserID:    u.ID,
+		ClientID:  &clientID,
+		UserAgent: "Note Desktop",
+		IPAddress: "127.0.0.1",
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	sessions, err := strg.Session().GetAllByClient(clientID)
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	require.Equal(t, session.ID, sessions[0].ID)
+	require.Equal(t, clientID, *sessions[0].ClientID)
+}
@@ -0,0 +1,199 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/mirasildev/note_project/storage/repo"
+)
+
+// uniqueViolation is the postgres error code of a violated unique constraint
+const uniqueViolation = "23505"
+
+type tagRepo struct {
+	db *sqlx.DB
+}
+
+func NewTag(db *sqlx.DB) repo.TagStorageI {
+	return &tagRepo{
+		db: db,
+	}
+}
+
+const selectTags = `
+	SELECT
+		t.id,
+		t.user_id,
+		t.name,
+		t.created_at,
+		count(n.id)
+	FROM tags t
+	LEFT JOIN note_tags nt ON nt.tag_id=t.id
+	LEFT JOIN notes n ON n.id=nt.note_id AND n.deleted_at IS NULL
+`
+
+func scanTag(row interface{ Scan(...interface{}) error }) (*repo.Tag, error) {
+	var result repo.Tag
+
+	err := row.Scan(
+		&result.ID,
+		&result.UserID,
+		&result.Name,
+		&result.CreatedAt,
+		&result.NoteCount,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (tr *tagRepo) Get(id, userID int64) (*repo.Tag, error) {
+	query := selectTags + " WHERE t.id=$1 AND t.user_id=$2 GROUP BY t.id"
+
+	return scanTag(tr.db.QueryRow(query, id, userID))
+}
+
+func (tr *tagRepo) GetByName(name string, userID int64) (*repo.Tag, error) {
+	query := selectTags + " WHERE t.name=$1 AND t.user_id=$2 GROUP BY t.id"
+
+	return scanTag(tr.db.QueryRow(query, name, userID))
+}
+
+func (tr *tagRepo) GetAll(userID int64) ([]*repo.Tag, error) {
+	query := selectTags + " WHERE t.user_id=$1 GROUP BY t.id ORDER BY t.name"
+
+	rows, err := tr.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]*repo.Tag, 0)
+	for rows.Next() {
+		t, err := scanTag(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, t)
+	}
+
+	return result, rows.Err()
+}
+
+// bumpTaggedNotes increments the version of the notes with the tag,
+// since their tags change along with it
+func bumpTaggedNotes(tx *sqlx.Tx, tagID int64) error {
+	query := "UPDATE notes SET version=version+1 WHERE id IN (SELECT note_id FROM note_tags WHERE tag_id=$1)"
+
+	_, err := tx.Exec(query, tagID)
+	return err
+}
+
+func (tr *tagRepo) Rename(id, userID int64, name string) (*repo.Tag, error) {
+	tx, err := tr.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := "UPDATE tags SET name=$1 WHERE id=$2 AND user_id=$3"
+
+	err = execAffectingRows(tx, query, name, id, userID)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return nil, repo.ErrTagNameTaken
+		}
+
+		return nil, err
+	}
+
+	err = bumpTaggedNotes(tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return tr.Get(id, userID)
+}
+
+func (tr *tagRepo) Merge(sourceID, targetID, userID int64) (*repo.Tag, error) {
+	tx, err := tr.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// lock both tags so that they can't be renamed or merged meanwhile
+	var count int
+	err = tx.QueryRow(
+		"SELECT count(1) FROM (SELECT id FROM tags WHERE id IN ($1, $2) AND user_id=$3 FOR UPDATE) t",
+		sourceID, targetID, userID,
+	).Scan(&count)
+	if err != nil {
+		return nil, err
+	}
+
+	if count != 2 {
+		return nil, sql.ErrNoRows
+	}
+
+	err = bumpTaggedNotes(tx, sourceID)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		INSERT INTO note_tags(note_id, tag_id)
+		SELECT note_id, $1 FROM note_tags WHERE tag_id=$2
+		ON CONFLICT DO NOTHING
+	`
+	_, err = tx.Exec(query, targetID, sourceID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec("DELETE FROM tags WHERE id=$1", sourceID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return tr.Get(targetID, userID)
+}
+
+func (tr *tagRepo) Delete(id, userID int64) error {
+	tx, err := tr.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// the notes are bumped first, deleting the tag removes its note_tags
+	err = bumpTaggedNotes(tx, id)
+	if err != nil {
+		return err
+	}
+
+	query := "DELETE FROM tags WHERE id=$1 AND user_id=$2"
+
+	err = execAffectingRows(tx, query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,136 @@
+package postgres_test
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/go-faker/faker/v4"
+	"github.com/mirasildev/note_project/storage/repo"
+	"github.com/stretchr/testify/require"
+)
+
+func createTaggedNote(t *testing.T, userID int64, tags ...string) *repo.Note {
+	n, err := strg.Note().Create(&repo.Note{
+		UserID:      userID,
+		Title:       faker.Sentence(),
+		Description: faker.Sentence(),
+		CreatedAt:   time.Now(),
+		Tags:        tags,
+	})
+	require.NoError(t, err)
+	require.ElementsMatch(t, tags, n.Tags)
+
+	return n
+}
+
+func TestNoteTags(t *testing.T) {
+	u := createUser(t)
+
+	n := createTaggedNote(t, u.ID, "work", "ideas")
+	createTaggedNote(t, u.ID, "work")
+	createTaggedNote(t, u.ID, "home")
+
+	note, err := strg.Note().Get(n.ID, u.ID)
+	require.NoError(t, err)
+	require.Equal(t, []string{"ideas", "work"}, note.Tags)
+
+	filter := func(matchAll bool, tags ...string) *repo.GetAllNotesResult {
+		result, err := strg.Note().GetAllNotes(&repo.GetAllNotesParams{
+			UserID:       u.ID,
+			Limit:        10,
+			Page:         1,
+			Tags:         tags,
+			TagsMatchAll: matchAll,
+		})
+		require.NoError(t, err)
+		return result
+	}
+
+	require.Equal(t, int32(3), filter(false, "work", "home").Count)
+	require.Equal(t, int32(1), filter(true, "work", "ideas").Count)
+	require.Equal(t, int32(0), filter(true, "work", "home").Count)
+
+	// nil tags are left as they are, an empty list removes them
+	updated, err := strg.Note().Update(&repo.Note{
+		ID:          n.ID,
+		UserID:      u.ID,
+		Title:       faker.Sentence(),
+		Description: faker.Sentence(),
+		UpdatedAt:   time.Now(),
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"ideas", "work"}, updated.Tags)
+
+	updated, err = strg.Note().Update(&repo.Note{
+		ID:          n.ID,
+		UserID:      u.ID,
+		Title:       faker.Sentence(),
+		Description: faker.Sentence(),
+		UpdatedAt:   time.Now(),
+		Tags:        []string{},
+	})
+	require.NoError(t, err)
+	require.Empty(t, updated.Tags)
+
+	deleteUser(u.ID, t)
+}
+
+func TestRenameAndMergeTags(t *testing.T) {
+	u := createUser(t)
+
+	both := createTaggedNote(t, u.ID, "work", "job")
+	jobOnly := createTaggedNote(t, u.ID, "job")
+	untagged := createTaggedNote(t, u.ID)
+
+	// every change of a tag is a new version of the notes with it
+	requireVersion := func(n *repo.Note, version int64) {
+		note, err := strg.Note().Get(n.ID, u.ID)
+		require.NoError(t, err)
+		require.Equal(t, version, note.Version)
+	}
+
+	work, err := strg.Tag().GetByName("work", u.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), work.NoteCount)
+
+	job, err := strg.Tag().GetByName("job", u.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), job.NoteCount)
+
+	_, err = strg.Tag().Rename(work.ID, u.ID, "job")
+	require.ErrorIs(t, err, repo.ErrTagNameTaken)
+	requireVersion(both, 1)
+
+	renamed, err := strg.Tag().Rename(work.ID, u.ID, "office")
+	require.NoError(t, err)
+	require.Equal(t, "office", renamed.Name)
+	requireVersion(both, 2)
+	requireVersion(jobOnly, 1)
+
+	merged, err := strg.Tag().Merge(job.ID, work.ID, u.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), merged.NoteCount)
+	requireVersion(both, 3)
+	requireVersion(jobOnly, 2)
+
+	_, err = strg.Tag().Get(job.ID, u.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	other := createUser(t)
+	_, err = strg.Tag().Merge(work.ID, work.ID+1, other.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	tags, err := strg.Tag().GetAll(u.ID)
+	require.NoError(t, err)
+	require.Len(t, tags, 1)
+
+	err = strg.Tag().Delete(work.ID, u.ID)
+	require.NoError(t, err)
+	requireVersion(both, 4)
+	requireVersion(jobOnly, 3)
+	requireVersion(untagged, 1)
+
+	deleteUser(other.ID, t)
+	deleteUser(u.ID, t)
+}
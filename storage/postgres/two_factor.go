@@ -0,0 +1,123 @@
+package postgres
+
+import (
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/mirasildev/note_project/storage/repo"
+)
+
+type twoFactorRepo struct {
+	db *sqlx.DB
+}
+
+func NewTwoFactor(db *sqlx.DB) repo.TwoFactorStorageI {
+	return &twoFactorRepo{
+		db: db,
+	}
+}
+
+func (tr *twoFactorRepo) Get(userID int64) (*repo.TwoFactor, error) {
+	var result repo.TwoFactor
+
+	query := `
+		SELECT
+			id,
+			totp_secret,
+			totp_enabled
+		FROM users
+		WHERE id=$1
+	`
+
+	err := tr.db.QueryRow(query, userID).Scan(
+		&result.UserID,
+		&result.Secret,
+		&result.Enabled,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// SetSecret stores a new secret which stays disabled until Enable is called
+func (tr *twoFactorRepo) SetSecret(userID int64, secret string) error {
+	query := "UPDATE users SET totp_secret=$1, totp_enabled=FALSE WHERE id=$2"
+	return execAffectingRows(tr.db, query, secret, userID)
+}
+
+func (tr *twoFactorRepo) Enable(userID int64) error {
+	query := "UPDATE users SET totp_enabled=TRUE WHERE id=$1 AND totp_secret IS NOT NULL"
+	return execAffectingRows(tr.db, query, userID)
+}
+
+func (tr *twoFactorRepo) Disable(userID int64) error {
+	tx, err := tr.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("DELETE FROM recovery_codes WHERE user_id=$1", userID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("UPDATE users SET totp_secret=NULL, totp_enabled=FALSE WHERE id=$1", userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ReplaceRecoveryCodes drops the previous recovery codes of the user and stores the new ones
+func (tr *twoFactorRepo) ReplaceRecoveryCodes(userID int64, codeHashes []string) error {
+	tx, err := tr.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("DELETE FROM recovery_codes WHERE user_id=$1", userID)
+	if err != nil {
+		return err
+	}
+
+	for _, codeHash := range codeHashes {
+		_, err = tx.Exec("INSERT INTO recovery_codes(user_id, code_hash) VALUES($1, $2)", userID, codeHash)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// UseRecoveryCode marks an unused recovery code as used, or returns sql.ErrNoRows
+func (tr *twoFactorRepo) UseRecoveryCode(userID int64, codeHash string) error {
+	query := `
+		UPDATE recovery_codes SET used_at=CURRENT_TIMESTAMP
+		WHERE user_id=$1 AND code_hash=$2 AND used_at IS NULL
+	`
+	return execAffectingRows(tr.db, query, userID, codeHash)
+}
+
+func execAffectingRows(db sqlx.Execer, query string, args ...interface{}) error {
+	result, err := db.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+
+	rowsCount, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsCount == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
@@ -0,0 +1,41 @@
+package postgres_test
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTwoFactor(t *testing.T) {
+	u := createUser(t)
+	defer deleteUser(u.ID, t)
+
+	err := strg.TwoFactor().SetSecret(u.ID, "JBSWY3DPEHPK3PXP")
+	require.NoError(t, err)
+
+	twoFactor, err := strg.TwoFactor().Get(u.ID)
+	require.NoError(t, err)
+	require.False(t, twoFactor.Enabled)
+	require.Equal(t, "JBSWY3DPEHPK3PXP", *twoFactor.Secret)
+
+	err = strg.TwoFactor().Enable(u.ID)
+	require.NoError(t, err)
+
+	err = strg.TwoFactor().ReplaceRecoveryCodes(u.ID, []string{"hash1", "hash2"})
+	require.NoError(t, err)
+
+	err = strg.TwoFactor().UseRecoveryCode(u.ID, "hash1")
+	require.NoError(t, err)
+
+	err = strg.TwoFactor().UseRecoveryCode(u.ID, "hash1")
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	err = strg.TwoFactor().Disable(u.ID)
+	require.NoError(t, err)
+
+	twoFactor, err = strg.TwoFactor().Get(u.ID)
+	require.NoError(t, err)
+	require.False(t, twoFactor.Enabled)
+	require.Nil(t, twoFactor.Secret)
+}
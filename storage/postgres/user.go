@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/mirasildev/note_project/storage/repo"
@@ -28,11 +29,16 @@ func (ur *userRepo) Create(u *repo.User) (*repo.User, error) {
 			email,
 			password,
 			image_url,
+			role,
 			created_at	                  
-		) VALUES($1, $2, $3, $4, $5, $6, $7)
+		) VALUES($1, $2, $3, $4, $5, $6, $7, $8)
 		RETURNING id, created_at 
 	`
 
+	if u.Role == "" {
+		u.Role = repo.UserRoleUser
+	}
+
 	err := ur.db.QueryRow(
 		query,
 		u.FirstName,
@@ -41,6 +47,7 @@ func (ur *userRepo) Create(u *repo.User) (*repo.User, error) {
 		u.Email,
 		u.Password,
 		u.ImageURL,
+		u.Role,
 		u.CreatedAt,
 	).Scan(
 		&u.ID,
@@ -65,7 +72,10 @@ func (ur *userRepo) Get(id int64) (*repo.User, error) {
 			email,
 			password,
 			image_url,
-			created_at
+			role,
+			created_at,
+			deletion_scheduled_at,
+			note_revision_limit
 		FROM users
 		WHERE id=$1
 	`
@@ -79,7 +89,10 @@ func (ur *userRepo) Get(id int64) (*repo.User, error) {
 		&result.Email,
 		&result.Password,
 		&result.ImageURL,
+		&result.Role,
 		&result.CreatedAt,
+		&result.DeletionScheduledAt,
+		&result.NoteRevisionLimit,
 	)
 	if err != nil {
 		return nil, err
@@ -116,7 +129,10 @@ func (ur *userRepo) GetAllUsers(params *repo.GetAllUsersParams) (*repo.GetAllUse
 			email,
 			password,
 			image_url,
-			created_at
+			role,
+			created_at,
+			deletion_scheduled_at,
+			note_revision_limit
 		FROM users
 		` + filter + `
 		ORDER BY created_at desc
@@ -141,7 +157,10 @@ func (ur *userRepo) GetAllUsers(params *repo.GetAllUsersParams) (*repo.GetAllUse
 			&u.Email,
 			&u.Password,
 			&u.ImageURL,
+			&u.Role,
 			&u.CreatedAt,
+			&u.DeletionScheduledAt,
+			&u.NoteRevisionLimit,
 		)
 		if err != nil {
 			// log.Print(err)
@@ -166,11 +185,10 @@ func (ur *userRepo) Update(u *repo.User) (*repo.User, error) {
 			first_name=$1,
 			last_name=$2,
 			phone_number=$3,
-			email=$4,
-			image_url=$5
-		WHERE id=$6
+			image_url=$4
+		WHERE id=$5
 		RETURNING id, first_name, last_name, phone_number, email,
-		image_url, created_at
+		image_url, role, created_at, deletion_scheduled_at, note_revision_limit
 	`
 	log.Print(query)
 	var result repo.User
@@ -179,7 +197,6 @@ func (ur *userRepo) Update(u *repo.User) (*repo.User, error) {
 		u.FirstName,
 		u.LastName,
 		u.PhoneNumber,
-		u.Email,
 		u.ImageURL,
 		u.ID,
 	).Scan(
@@ -189,7 +206,10 @@ func (ur *userRepo) Update(u *repo.User) (*repo.User, error) {
 		&result.PhoneNumber,
 		&result.Email,
 		&result.ImageURL,
+		&result.Role,
 		&result.CreatedAt,
+		&result.DeletionScheduledAt,
+		&result.NoteRevisionLimit,
 	)
 	if err != nil {
 		return nil, err
@@ -199,28 +219,8 @@ func (ur *userRepo) Update(u *repo.User) (*repo.User, error) {
 }
 
 func (ur *userRepo) Delete(id int64) error {
-	queryDeleteNotes := "DELETE FROM notes WHERE user_id=$1"
-	_, err := ur.db.Exec(queryDeleteNotes, id)
-	if err != nil {
-		return err
-	}
-
-	query := "DELETE FROM users WHERE id=$1"
-	result, err := ur.db.Exec(query, id)
-	if err != nil {
-		return err
-	}
-
-	rowsCount, err := result.RowsAffected()
-	if err != nil {
-		return err
-	}
-
-	if rowsCount == 0 {
-		return sql.ErrNoRows
-	}
-
-	return nil
+	_, err := ur.Purge(id)
+	return err
 }
 
 func (ur *userRepo) GetByEmail(email string) (*repo.User, error) {
@@ -235,7 +235,10 @@ func (ur *userRepo) GetByEmail(email string) (*repo.User, error) {
 			email,
 			password,
 			image_url,
-			created_at
+			role,
+			created_at,
+			deletion_scheduled_at,
+			note_revision_limit
 		FROM users
 		WHERE email=$1
 	`
@@ -248,7 +251,10 @@ func (ur *userRepo) GetByEmail(email string) (*repo.User, error) {
 		&result.Email,
 		&result.Password,
 		&result.ImageURL,
+		&result.Role,
 		&result.CreatedAt,
+		&result.DeletionScheduledAt,
+		&result.NoteRevisionLimit,
 	)
 	if err != nil {
 		return nil, err
@@ -256,4 +262,127 @@ func (ur *userRepo) GetByEmail(email string) (*repo.User, error) {
 
 	return &result, nil
 
-}
\ No newline at end of file
+}
+
+func (ur *userRepo) UpdatePassword(userID int64, password string) error {
+	query := "UPDATE users SET password=$1 WHERE id=$2"
+	result, err := ur.db.Exec(query, password, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsCount, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsCount == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (ur *userRepo) UpdateEmail(userID int64, email string) error {
+	query := "UPDATE users SET email=$1 WHERE id=$2"
+	result, err := ur.db.Exec(query, email, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsCount, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsCount == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (ur *userRepo) UpdateNoteRevisionLimit(userID int64, limit *int) error {
+	query := "UPDATE users SET note_revision_limit=$1 WHERE id=$2"
+	return execAffectingRows(ur.db, query, limit, userID)
+}
+
+func (ur *userRepo) ScheduleDeletion(userID int64, at time.Time) error {
+	query := "UPDATE users SET deletion_scheduled_at=$1 WHERE id=$2"
+	return execAffectingRows(ur.db, query, at, userID)
+}
+
+func (ur *userRepo) CancelDeletion(userID int64, scheduledAt time.Time) error {
+	// links carry the time in whole seconds
+	query := "UPDATE users SET deletion_scheduled_at=NULL WHERE id=$1 AND date_trunc('second', deletion_scheduled_at)=$2"
+	return execAffectingRows(ur.db, query, userID, scheduledAt)
+}
+
+func (ur *userRepo) GetScheduledForDeletion(before time.Time) ([]int64, error) {
+	query := "SELECT id FROM users WHERE deletion_scheduled_at <= $1 ORDER BY deletion_scheduled_at"
+
+	rows, err := ur.db.Query(query, before)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]int64, 0)
+	for rows.Next() {
+		var id int64
+
+		err := rows.Scan(&id)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, id)
+	}
+
+	return result, rows.Err()
+}
+
+func (ur *userRepo) Purge(id int64) (*repo.PurgedUser, error) {
+	tx, err := ur.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	result := repo.PurgedUser{
+		ID: id,
+	}
+
+	err = tx.QueryRow("SELECT email FROM users WHERE id=$1 FOR UPDATE", id).Scan(&result.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Select(&result.SessionIDs, "SELECT id FROM sessions WHERE user_id=$1", id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Select(&result.FilePaths, "DELETE FROM files WHERE user_id=$1 RETURNING path", id)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec("DELETE FROM notes WHERE user_id=$1", id)
+	if err != nil {
+		return nil, err
+	}
+
+	// The rest of the data of the user is deleted on cascade
+	_, err = tx.Exec("DELETE FROM users WHERE id=$1", id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
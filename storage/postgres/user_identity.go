@@ -0,0 +1,71 @@
+package postgres
+
+import (
+	"github.com/jmoiron/sqlx"
+	"github.com/mirasildev/note_project/storage/repo"
+)
+
+type userIdentityRepo struct {
+	db *sqlx.DB
+}
+
+func NewUserIdentity(db *sqlx.DB) repo.UserIdentityStorageI {
+	return &userIdentityRepo{
+		db: db,
+	}
+}
+
+func (ur *userIdentityRepo) Create(i *repo.UserIdentity) (*repo.UserIdentity, error) {
+	query := `
+		INSERT INTO user_identities(
+			user_id,
+			provider,
+			subject,
+			email
+		) VALUES($1, $2, $3, $4)
+		RETURNING id, created_at
+	`
+
+	err := ur.db.QueryRow(
+		query,
+		i.UserID,
+		i.Provider,
+		i.Subject,
+		i.Email,
+	).Scan(&i.ID, &i.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return i, nil
+}
+
+func (ur *userIdentityRepo) Get(provider, subject string) (*repo.UserIdentity, error) {
+	var result repo.UserIdentity
+
+	query := `
+		SELECT
+			id,
+			user_id,
+			provider,
+			subject,
+			email,
+			created_at
+		FROM user_identities
+		WHERE provider=$1 AND subject=$2
+	`
+
+	err := ur.db.QueryRow(query, provider, subject).Scan(
+		&result.ID,
+		&result.UserID,
+		&result.Provider,
+		&result.Subject,
+		&result.Email,
+		&result.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
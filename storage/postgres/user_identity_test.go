@@ -0,0 +1,31 @@
+package postgres_test
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/go-faker/faker/v4"
+	"github.com/mirasildev/note_project/storage/repo"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserIdentity(t *testing.T) {
+	u := createUser(t)
+	defer deleteUser(u.ID, t)
+
+	identity, err := strg.UserIdentity().Create(&repo.UserIdentity{
+		UserID:   u.ID,
+		Provider: "google",
+		Subject:  faker.UUIDDigit(),
+		Email:    u.Email,
+	})
+	require.NoError(t, err)
+	require.NotZero(t, identity.ID)
+
+	found, err := strg.UserIdentity().Get("google", identity.Subject)
+	require.NoError(t, err)
+	require.Equal(t, u.ID, found.UserID)
+
+	_, err = strg.UserIdentity().Get("okta", identity.Subject)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
@@ -1,6 +1,7 @@
 package postgres_test
 
 import (
+	"database/sql"
 	"testing"
 	"time"
 
@@ -40,11 +41,11 @@ func updateUser(t *testing.T) *repo.User {
 		FirstName:   faker.FirstName(),
 		LastName:    faker.LastName(),
 		PhoneNumber: &phone,
-		Email:       faker.Email(),
 		ImageURL:    &image,
 	})
 	require.NoError(t, err)
 	require.NotEmpty(t, user)
+	require.Equal(t, u.Email, user.Email)
 
 	return user
 }
@@ -52,6 +53,7 @@ func TestCreateUser(t *testing.T) {
 	user := createUser(t)
 	deleteUser(user.ID, t)
 	require.NotEmpty(t, user)
+	require.Equal(t, repo.UserRoleUser, user.Role)
 }
 
 func TestGetUser(t *testing.T) {
@@ -77,3 +79,92 @@ func TestGetAllUser(t *testing.T) {
 	require.NotEmpty(t, users)
 
 }
+
+func TestUpdateUserPassword(t *testing.T) {
+	u := createUser(t)
+	defer deleteUser(u.ID, t)
+
+	err := strg.User().UpdatePassword(u.ID, faker.Password())
+	require.NoError(t, err)
+}
+
+func TestUpdateUserEmail(t *testing.T) {
+	u := createUser(t)
+	defer deleteUser(u.ID, t)
+
+	email := faker.Email()
+	err := strg.User().UpdateEmail(u.ID, email)
+	require.NoError(t, err)
+
+	user, err := strg.User().Get(u.ID)
+	require.NoError(t, err)
+	require.Equal(t, email, user.Email)
+}
+
+func TestScheduleUserDeletion(t *testing.T) {
+	u := createUser(t)
+	defer deleteUser(u.ID, t)
+
+	deletionAt := time.Now().Add(-time.Minute)
+
+	err := strg.User().ScheduleDeletion(u.ID, deletionAt)
+	require.NoError(t, err)
+
+	user, err := strg.User().Get(u.ID)
+	require.NoError(t, err)
+	require.NotNil(t, user.DeletionScheduledAt)
+
+	ids, err := strg.User().GetScheduledForDeletion(time.Now())
+	require.NoError(t, err)
+	require.Contains(t, ids, u.ID)
+
+	// the link of an earlier deletion
+	err = strg.User().CancelDeletion(u.ID, time.Unix(deletionAt.Add(-time.Hour).Unix(), 0))
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	err = strg.User().CancelDeletion(u.ID, time.Unix(deletionAt.Unix(), 0))
+	require.NoError(t, err)
+
+	err = strg.User().CancelDeletion(u.ID, time.Unix(deletionAt.Unix(), 0))
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	ids, err = strg.User().GetScheduledForDeletion(time.Now())
+	require.NoError(t, err)
+	require.NotContains(t, ids, u.ID)
+}
+
+func TestPurgeUser(t *testing.T) {
+	u := createUser(t)
+
+	_, err := strg.Note().Create(&repo.Note{
+		UserID:      u.ID,
+		Title:       faker.Sentence(),
+		Description: faker.Sentence(),
+		CreatedAt:   time.Now(),
+	})
+	require.NoError(t, err)
+
+	file, err := strg.File().Create(&repo.File{
+		ID:     faker.UUIDDigit(),
+		UserID: u.ID,
+		Path:   "/media/" + faker.UUIDDigit() + ".png",
+	})
+	require.NoError(t, err)
+
+	session := createSession(t, u.ID)
+
+	purged, err := strg.User().Purge(u.ID)
+	require.NoError(t, err)
+	require.Equal(t, u.Email, purged.Email)
+	require.Equal(t, []string{file.Path}, purged.FilePaths)
+	require.Equal(t, []string{session.ID}, purged.SessionIDs)
+
+	_, err = strg.User().Get(u.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	_, err = strg.Session().Get(session.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	_, err = strg.User().Purge(u.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
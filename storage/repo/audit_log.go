@@ -0,0 +1,44 @@
+package repo
+
+import "time"
+
+// Events of the audit log
+const (
+	AuditEventRegister        = "register"
+	AuditEventVerify          = "verify"
+	AuditEventLoginSucceeded  = "login_succeeded"
+	AuditEventLoginFailed     = "login_failed"
+	AuditEventPasswordChanged = "password_changed"
+	AuditEventTokenRevoked    = "token_revoked"
+)
+
+// AuditLog is a security relevant event. The log is append-only.
+type AuditLog struct {
+	ID int64
+	// UserID is nil for events of unknown users, such as failed logins
+	UserID    *int64
+	Email     string
+	Event     string
+	IPAddress string
+	UserAgent string
+	CreatedAt time.Time
+}
+
+type GetAllAuditLogsParams struct {
+	Limit  int32
+	Page   int32
+	UserID *int64
+	Event  string
+	From   *time.Time
+	To     *time.Time
+}
+
+type GetAllAuditLogsResult struct {
+	AuditLogs []*AuditLog
+	Count     int32
+}
+
+type AuditLogStorageI interface {
+	Create(l *AuditLog) (*AuditLog, error)
+	GetAll(params *GetAllAuditLogsParams) (*GetAllAuditLogsResult, error)
+}
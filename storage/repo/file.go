@@ -0,0 +1,16 @@
+package repo
+
+import "time"
+
+// File is an upload of a user, stored under ./media
+type File struct {
+	ID     string
+	UserID int64
+	// Path is relative to the working directory, such as /media/{id}.png
+	Path      string
+	CreatedAt time.Time
+}
+
+type FileStorageI interface {
+	Create(f *File) (*File, error)
+}
@@ -1,22 +1,53 @@
 package repo
 
-import "time"
+import (
+	"errors"
+	"time"
+)
+
+// ErrVersionMismatch is returned when a note was changed
+// since the client read the version it tries to write
+var ErrVersionMismatch = errors.New("note version mismatch")
 
 type Note struct {
 	ID          int64
 	UserID      int64
+	NotebookID  *int64
 	Title       string
 	Description string
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
 	DeletedAt   *time.Time
+	// Version grows with every change of the note. When it is set on
+	// Update, the note is only updated if it still has this version
+	Version int64
+	// Tags are sorted by name. Update leaves the tags as they are when Tags is nil
+	Tags []string
+	// Highlight is only set for notes found by a search
+	Highlight *NoteHighlight
+}
+
+// NoteHighlight holds the HTML escaped parts of a note that matched a search,
+// with the matched words wrapped in <mark> tags
+type NoteHighlight struct {
+	Title       string
+	Description string
 }
 
 type GetAllNotesParams struct {
 	UserID int64
 	Limit int32
 	Page int32
+	// Search is a web search style query: words, "quoted phrases", or and -exclusions.
+	// When it is set the notes are ranked by relevance
 	Search string
+	// Tags filters the notes which have any of the tags, or all of them with TagsMatchAll
+	Tags         []string
+	TagsMatchAll bool
+	// NotebookID filters the notes of the notebook, and of the
+	// notebooks inside of it with Recursive
+	NotebookID *int64
+	Recursive  bool
 }
 
 type GetAllNotesResult struct {
@@ -24,10 +55,23 @@ type GetAllNotesResult struct {
 	Count int32
 }
 
+// NoteStorageI is scoped to the owner of the notes: every method takes
+// the user id and returns sql.ErrNoRows for notes of other users.
 type NoteStorageI interface {
 	Create(n *Note) (*Note, error)
-	Get(id int64) (*Note, error)
+	Get(id, userID int64) (*Note, error)
 	GetAllNotes(params *GetAllNotesParams) (*GetAllNotesResult, error)
+	// Update returns ErrVersionMismatch when n.Version is set and is not the current version
 	Update(n *Note) (*Note, error)
-	Delete(id int64) error
+	// Move puts the note into the notebook, or out of any notebook when notebookID is nil
+	Move(id, userID int64, notebookID *int64) error
+	// Delete moves the note to the trash. Like on Update, a version other
+	// than 0 has to match the current one, otherwise ErrVersionMismatch is returned
+	Delete(id, userID, version int64) error
+	GetTrash(params *GetAllNotesParams) (*GetAllNotesResult, error)
+	Restore(id, userID int64) error
+	// DeletePermanently deletes a note that is already in the trash
+	DeletePermanently(id, userID int64) error
+	// PurgeTrash deletes the notes moved to the trash before the given time
+	PurgeTrash(before time.Time) (int64, error)
 }
@@ -0,0 +1,28 @@
+package repo
+
+import "time"
+
+// NoteRevision is the content of a note after it was created or updated.
+// Numbers start from 1 and grow with every update of the note
+type NoteRevision struct {
+	ID          int64
+	NoteID      int64
+	Number      int
+	Title       string
+	Description string
+	// AuthorID is nil when the author was deleted
+	AuthorID  *int64
+	CreatedAt time.Time
+}
+
+// NoteRevisionStorageI is scoped to the owner of the notes like NoteStorageI.
+// The revisions are written by NoteStorageI.Create and NoteStorageI.Update
+type NoteRevisionStorageI interface {
+	// GetAll returns the revisions of the note, newest first
+	GetAll(noteID, userID int64) ([]*NoteRevision, error)
+	Get(noteID, userID int64, number int) (*NoteRevision, error)
+	// Prune deletes all but the newest keep revisions of the note
+	Prune(noteID int64, keep int) error
+	// PruneAll prunes the revisions of every note of the user
+	PruneAll(userID int64, keep int) error
+}
@@ -0,0 +1,37 @@
+package repo
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrNotebookCycle is returned when a notebook is moved into itself
+// or into one of the notebooks inside of it
+var ErrNotebookCycle = errors.New("notebook can't be moved into itself")
+
+// Notebook groups notes. Notebooks can be nested, the ones
+// without a parent are at the top level
+type Notebook struct {
+	ID        int64
+	UserID    int64
+	ParentID  *int64
+	Name      string
+	NoteCount int64
+	CreatedAt time.Time
+	UpdatedAt *time.Time
+}
+
+// NotebookStorageI is scoped to the owner of the notebooks like NoteStorageI.
+// NoteCount only counts the notes right in the notebook which are not in the trash.
+type NotebookStorageI interface {
+	Create(n *Notebook) (*Notebook, error)
+	Get(id, userID int64) (*Notebook, error)
+	// GetAll returns all of the notebooks of the user in one query, sorted by name
+	GetAll(userID int64) ([]*Notebook, error)
+	Rename(id, userID int64, name string) (*Notebook, error)
+	// Move puts the notebook into the parent, or to the top level when parentID is nil
+	Move(id, userID int64, parentID *int64) (*Notebook, error)
+	// Delete deletes the notebook with the notebooks inside of it.
+	// The notes are kept without a notebook
+	Delete(id, userID int64) error
+}
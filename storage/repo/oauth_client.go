@@ -0,0 +1,24 @@
+package repo
+
+import "time"
+
+// OAuthClient is a third-party app which may ask users for access to their notes
+type OAuthClient struct {
+	ID     string
+	UserID int64
+	Name   string
+	// SecretHash is empty for public clients, such as browser extensions and
+	// desktop apps, which can't keep a secret and rely on PKCE alone
+	SecretHash   *string
+	RedirectURIs []string
+	// Scopes are the most a user may grant to the client
+	Scopes    []string
+	CreatedAt time.Time
+}
+
+type OAuthClientStorageI interface {
+	Create(c *OAuthClient) (*OAuthClient, error)
+	Get(id string) (*OAuthClient, error)
+	GetAll(userID int64) ([]*OAuthClient, error)
+	Delete(id string, userID int64) error
+}
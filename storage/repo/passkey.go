@@ -0,0 +1,22 @@
+package repo
+
+import "time"
+
+type Passkey struct {
+	// ID is the base64url encoded WebAuthn credential id
+	ID         string
+	UserID     int64
+	Name       string
+	PublicKey  []byte
+	SignCount  uint32
+	LastUsedAt *time.Time
+	CreatedAt  time.Time
+}
+
+type PasskeyStorageI interface {
+	Create(p *Passkey) (*Passkey, error)
+	Get(id string) (*Passkey, error)
+	GetAll(userID int64) ([]*Passkey, error)
+	UpdateSignCount(id string, signCount uint32, lastUsedAt time.Time) error
+	Delete(id string, userID int64) error
+}
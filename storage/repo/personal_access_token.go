@@ -0,0 +1,23 @@
+package repo
+
+import "time"
+
+type PersonalAccessToken struct {
+	ID         string
+	UserID     int64
+	Name       string
+	TokenHash  string
+	Scopes     []string
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	CreatedAt  time.Time
+}
+
+type PersonalAccessTokenStorageI interface {
+	Create(t *PersonalAccessToken) (*PersonalAccessToken, error)
+	GetByHash(tokenHash string) (*PersonalAccessToken, error)
+	GetAll(userID int64) ([]*PersonalAccessToken, error)
+	UpdateLastUsed(id string, lastUsedAt time.Time) error
+	Delete(id string, userID int64) error
+	DeleteAll(userID int64) error
+}
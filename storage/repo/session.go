@@ -0,0 +1,30 @@
+package repo
+
+import "time"
+
+// Session is one login of a user. Its id is shared by
+// the refresh tokens and access tokens issued for the login.
+type Session struct {
+	ID     string
+	UserID int64
+	// ClientID is set for sessions of third-party apps authorized by the user
+	ClientID   *string
+	UserAgent  string
+	IPAddress  string
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	ExpiresAt  time.Time
+	RevokedAt  *time.Time
+}
+
+type SessionStorageI interface {
+	Create(s *Session) (*Session, error)
+	Get(id string) (*Session, error)
+	// GetAll returns the sessions of the user which are neither revoked nor expired
+	GetAll(userID int64) ([]*Session, error)
+	// GetAllByClient returns the active sessions of all users of the client
+	GetAllByClient(clientID string) ([]*Session, error)
+	UpdateLastSeen(id string, lastSeenAt time.Time) error
+	Revoke(id string) error
+	RevokeAll(userID int64) error
+}
@@ -0,0 +1,32 @@
+package repo
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrTagNameTaken is returned when the user already has a tag with the name
+var ErrTagNameTaken = errors.New("tag name is already taken")
+
+// Tag categorizes notes. Names are unique per user
+type Tag struct {
+	ID        int64
+	UserID    int64
+	Name      string
+	NoteCount int64
+	CreatedAt time.Time
+}
+
+// TagStorageI is scoped to the owner of the tags like NoteStorageI.
+// NoteCount only counts the notes which are not in the trash.
+type TagStorageI interface {
+	Get(id, userID int64) (*Tag, error)
+	GetByName(name string, userID int64) (*Tag, error)
+	GetAll(userID int64) ([]*Tag, error)
+	// Rename returns ErrTagNameTaken when another tag of the user has the name
+	Rename(id, userID int64, name string) (*Tag, error)
+	// Merge moves the notes of the source tag to the target tag
+	// and deletes the source tag in one transaction
+	Merge(sourceID, targetID, userID int64) (*Tag, error)
+	Delete(id, userID int64) error
+}
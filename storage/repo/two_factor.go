@@ -0,0 +1,16 @@
+package repo
+
+type TwoFactor struct {
+	UserID  int64
+	Secret  *string
+	Enabled bool
+}
+
+type TwoFactorStorageI interface {
+	Get(userID int64) (*TwoFactor, error)
+	SetSecret(userID int64, secret string) error
+	Enable(userID int64) error
+	Disable(userID int64) error
+	ReplaceRecoveryCodes(userID int64, codeHashes []string) error
+	UseRecoveryCode(userID int64, codeHash string) error
+}
@@ -2,6 +2,11 @@ package repo
 
 import "time"
 
+const (
+	UserRoleUser  = "user"
+	UserRoleAdmin = "admin"
+)
+
 type User struct {
 	ID          int64
 	FirstName   string
@@ -10,7 +15,14 @@ type User struct {
 	Email       string
 	Password    string
 	ImageURL    *string
+	Role        string
 	CreatedAt   time.Time
+	// DeletionScheduledAt is when the account will be purged,
+	// nil unless the user asked to delete it
+	DeletionScheduledAt *time.Time
+	// NoteRevisionLimit is how many revisions are kept per note,
+	// nil to use the default of the service
+	NoteRevisionLimit *int
 }
 
 type GetAllUsersParams struct {
@@ -24,6 +36,15 @@ type GetAllUsersResult struct {
 	Users []*User
 }
 
+// PurgedUser is what is left of a purged user outside of the database
+type PurgedUser struct {
+	ID         int64
+	Email      string
+	SessionIDs []string
+	// FilePaths are the uploads which still have to be removed from disk
+	FilePaths []string
+}
+
 type UserStorageI interface {
 	Create(n *User) (*User, error)
 	Get(id int64) (*User, error)
@@ -31,4 +52,15 @@ type UserStorageI interface {
 	Update(n *User) (*User, error)
 	Delete(id int64) error
 	GetByEmail(email string) (*User, error)
+	UpdatePassword(userID int64, password string) error
+	UpdateEmail(userID int64, email string) error
+	UpdateNoteRevisionLimit(userID int64, limit *int) error
+	ScheduleDeletion(userID int64, at time.Time) error
+	// CancelDeletion cancels the deletion only if it is scheduled at the given time,
+	// so a link sent for an earlier deletion can't cancel a later one
+	CancelDeletion(userID int64, scheduledAt time.Time) error
+	// GetScheduledForDeletion returns the ids of users whose deletion is due by the given time
+	GetScheduledForDeletion(before time.Time) ([]int64, error)
+	// Purge deletes the user with the notes, files and sessions in one transaction
+	Purge(id int64) (*PurgedUser, error)
 }
@@ -0,0 +1,18 @@
+package repo
+
+import "time"
+
+// UserIdentity links a user to an account at an external identity provider
+type UserIdentity struct {
+	ID        int64
+	UserID    int64
+	Provider  string
+	Subject   string
+	Email     string
+	CreatedAt time.Time
+}
+
+type UserIdentityStorageI interface {
+	Create(i *UserIdentity) (*UserIdentity, error)
+	Get(provider, subject string) (*UserIdentity, error)
+}
@@ -9,17 +9,50 @@ import (
 type StorageI interface {
 	User() repo.UserStorageI
 	Note() repo.NoteStorageI
+	TwoFactor() repo.TwoFactorStorageI
+	PersonalAccessToken() repo.PersonalAccessTokenStorageI
+	UserIdentity() repo.UserIdentityStorageI
+	Session() repo.SessionStorageI
+	Passkey() repo.PasskeyStorageI
+	OAuthClient() repo.OAuthClientStorageI
+	File() repo.FileStorageI
+	AuditLog() repo.AuditLogStorageI
+	Tag() repo.TagStorageI
+	Notebook() repo.NotebookStorageI
+	NoteRevision() repo.NoteRevisionStorageI
 }
 
 type storagePg struct {
-	userRepo repo.UserStorageI
-	noteRepo repo.NoteStorageI
+	userRepo                repo.UserStorageI
+	noteRepo                repo.NoteStorageI
+	twoFactorRepo           repo.TwoFactorStorageI
+	personalAccessTokenRepo repo.PersonalAccessTokenStorageI
+	userIdentityRepo        repo.UserIdentityStorageI
+	sessionRepo             repo.SessionStorageI
+	passkeyRepo             repo.PasskeyStorageI
+	oauthClientRepo         repo.OAuthClientStorageI
+	fileRepo                repo.FileStorageI
+	auditLogRepo            repo.AuditLogStorageI
+	tagRepo                 repo.TagStorageI
+	notebookRepo            repo.NotebookStorageI
+	noteRevisionRepo        repo.NoteRevisionStorageI
 }
 
 func NewStoragePg(db *sqlx.DB) StorageI {
 	return &storagePg{
-		userRepo: postgres.NewUser(db),
-		noteRepo: postgres.NewNote(db),
+		userRepo:                postgres.NewUser(db),
+		noteRepo:                postgres.NewNote(db),
+		twoFactorRepo:           postgres.NewTwoFactor(db),
+		personalAccessTokenRepo: postgres.NewPersonalAccessToken(db),
+		userIdentityRepo:        postgres.NewUserIdentity(db),
+		sessionRepo:             postgres.NewSession(db),
+		passkeyRepo:             postgres.NewPasskey(db),
+		oauthClientRepo:         postgres.NewOAuthClient(db),
+		fileRepo:                postgres.NewFile(db),
+		auditLogRepo:            postgres.NewAuditLog(db),
+		tagRepo:                 postgres.NewTag(db),
+		notebookRepo:            postgres.NewNotebook(db),
+		noteRevisionRepo:        postgres.NewNoteRevision(db),
 	}
 }
 
@@ -31,3 +64,46 @@ func (s *storagePg) Note() repo.NoteStorageI {
 	return s.noteRepo
 }
 
+func (s *storagePg) TwoFactor() repo.TwoFactorStorageI {
+	return s.twoFactorRepo
+}
+
+func (s *storagePg) PersonalAccessToken() repo.PersonalAccessTokenStorageI {
+	return s.personalAccessTokenRepo
+}
+
+func (s *storagePg) UserIdentity() repo.UserIdentityStorageI {
+	return s.userIdentityRepo
+}
+
+func (s *storagePg) Session() repo.SessionStorageI {
+	return s.sessionRepo
+}
+
+func (s *storagePg) Passkey() repo.PasskeyStorageI {
+	return s.passkeyRepo
+}
+
+func (s *storagePg) OAuthClient() repo.OAuthClientStorageI {
+	return s.oauthClientRepo
+}
+
+func (s *storagePg) File() repo.FileStorageI {
+	return s.fileRepo
+}
+
+func (s *storagePg) AuditLog() repo.AuditLogStorageI {
+	return s.auditLogRepo
+}
+
+func (s *storagePg) Tag() repo.TagStorageI {
+	return s.tagRepo
+}
+
+func (s *storagePg) Notebook() repo.NotebookStorageI {
+	return s.notebookRepo
+}
+
+func (s *storagePg) NoteRevision() repo.NoteRevisionStorageI {
+	return s.noteRevisionRepo
+}